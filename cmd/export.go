@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strava-intervals-description-sync/internal/intervals"
+	"strava-intervals-description-sync/internal/workoutfile"
+	"strings"
+)
+
+// runExport writes a planned workout of intervals.icu calendar as a workout file for trainers and watches
+func runExport(args []string) error {
+	flags, configPath := newFlagSet("export")
+	eventId := flags.Int("event", 0, "intervals.icu calendar event id of the planned workout")
+	format := flags.String("format", workoutfile.FormatZwo,
+		"workout file format, one of "+strings.Join(workoutfile.Formats, ", "))
+	output := flags.String("o", "", "output file, defaults to workout name in current directory, - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *eventId == 0 {
+		return errors.New("event id is required")
+	}
+	if !slices.Contains(workoutfile.Formats, *format) {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	exporter := workoutfile.NewExporter(intervals.NewClient(cfg.Intervals))
+	content, fileName, err := exporter.ExportEvent(*eventId, *format)
+	if err != nil {
+		return err
+	}
+
+	if *output == "-" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	if *output == "" {
+		*output = fileName
+	}
+	if err = os.WriteFile(*output, content, 0o644); err != nil {
+		return err
+	}
+	fmt.Println("Written", *output)
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strava-intervals-description-sync/internal/strava"
+	"text/tabwriter"
+)
+
+// runGear lists athlete's Strava gear, ids are needed for `sync.gear_rules`
+func runGear(args []string) error {
+	flags, configPath := newFlagSet("gear")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	athlete, err := newStravaClient(cfg).GetAthlete()
+	if err != nil {
+		return err
+	}
+	if len(athlete.Shoes) == 0 && len(athlete.Bikes) == 0 {
+		fmt.Println("No gear found, if you have gear on Strava re-authenticate to grant profile:read_all scope")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tTYPE\tNAME\tDEFAULT\tRETIRED\tDISTANCE")
+	printGear := func(gearType string, gear []strava.Gear) {
+		for _, g := range gear {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%t\t%.0fkm\n", g.Id, gearType, g.Name, g.Primary, g.Retired, g.Distance/1000)
+		}
+	}
+	printGear("shoes", athlete.Shoes)
+	printGear("bike", athlete.Bikes)
+	return w.Flush()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strava-intervals-description-sync/internal/history"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func runHistory(args []string) error {
+	flags, configPath := newFlagSet("history")
+	activityId := flags.Int64("activity", 0, "only show records of this Strava activity id")
+	status := flags.String("status", "", "only show records with this status (synced, skipped, failed)")
+	from := flags.String("from", "", "only show records started at or after this date (YYYY-MM-DD or RFC3339)")
+	to := flags.String("to", "", "only show records started before this date (YYYY-MM-DD or RFC3339)")
+	limit := flags.Int("limit", 20, "maximum number of records, 0 for no limit")
+	asJson := flags.Bool("json", false, "print full records as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	q := history.Query{StravaActivityId: *activityId, Status: history.Status(*status), Limit: *limit}
+	if q.From, err = history.ParseTime(*from); err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	if q.To, err = history.ParseTime(*to); err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+
+	store, err := history.Open(cfg.Storage.HistoryDb)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	records, err := store.List(q)
+	if err != nil {
+		return err
+	}
+
+	if *asJson {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STARTED\tACTIVITY\tACTION\tSTATUS\tINTERVALS ACTIVITY\tWORKOUT\tMATCHED BY\tERROR")
+	for _, r := range records {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%d\t%s\t%s\n", r.StartedAt.Local().Format(time.DateTime),
+			r.StravaActivityId, r.Action, r.Status, r.IntervalsActivityId, r.WorkoutId, r.WorkoutMatchStrategy,
+			strings.ReplaceAll(r.Error, "\n", " "))
+	}
+	return w.Flush()
+}
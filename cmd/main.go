@@ -1,117 +1,123 @@
 package main
 
 import (
-	"context"
-	"errors"
+	"flag"
+	"fmt"
 	"github.com/joho/godotenv"
 	"log"
-	"net/http"
 	"os"
-	"os/signal"
+	"strava-intervals-description-sync/internal/config"
+	"strava-intervals-description-sync/internal/history"
 	intervals2 "strava-intervals-description-sync/internal/intervals"
+	"strava-intervals-description-sync/internal/notify"
 	strava2 "strava-intervals-description-sync/internal/strava"
+	"strava-intervals-description-sync/internal/strava/persistence"
+	"strava-intervals-description-sync/internal/syncer"
+	"strava-intervals-description-sync/internal/workoutfile"
 	"strings"
-	"syscall"
-	"time"
+	// distroless image has no zoneinfo, activity timezones need the embedded database
+	_ "time/tzdata"
 )
 
+// command is a CLI subcommand, run receives arguments following the subcommand name
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands = []command{
+	{"serve", "run the webhook server (default)", runServe},
+	{"history", "query the sync history", runHistory},
+	{"unsync", "remove workout summaries from Strava activities", runUnsync},
+	{"render", "render workout summaries from local JSON files, offline", runRender},
+	{"export", "export a planned workout as ZWO, ERG, MRC or FIT file", runExport},
+	{"subscription", "list, create, delete or verify the Strava webhook subscription", runSubscription},
+	{"gear", "list Strava gear ids for gear rules", runGear},
+}
+
 func main() {
 	err := godotenv.Load()
 	if err != nil {
 		log.Println("Error loading .env file")
 	}
 
-	server := &http.Server{
-		Addr: ":5001",
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	http.HandleFunc(strava2.WebhookUrl, handleWebhookRequest)
-	http.HandleFunc(strava2.InitiateAuthenticationUrl, strava2.HandleAuthentication)
-	http.HandleFunc(strava2.AuthenticationCallbackUrl, strava2.HandleAuthenticationCallback)
-
-	go func() {
-		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("HTTP server error: %v", err)
-		}
-		log.Println("Stopped serving new connections.")
-	}()
-
-	if err = strava2.InitiateWebhookRegistration(); err != nil {
-		if err = server.Shutdown(context.Background()); err != nil {
-			log.Fatalf("HTTP shutdown error: %v", err)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err = cmd.run(args); err != nil {
+				log.Fatalf("%s: %v", name, err)
+			}
+			return
 		}
 	}
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
-
-	shutdownCtx, shutdownRelease := context.WithTimeout(context.Background(), 5*time.Second)
-	defer shutdownRelease()
-
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Fatalf("HTTP shutdown error: %v", err)
+	fmt.Fprintf(os.Stderr, "Unknown command %q, available commands:\n", name)
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.description)
 	}
-
-	log.Println("Stopped serving new connections.")
+	os.Exit(2)
 }
 
-func handleWebhookRequest(w http.ResponseWriter, req *http.Request) {
-	if req.Method == http.MethodGet {
-		strava2.HandleWebhookRegistrationRequest(w, req)
-	} else if req.Method == http.MethodPost {
-		shouldProcess, stravaActivityId := strava2.ShouldProcessWebhook(w, req)
-		if shouldProcess {
-			log.Println("Received webhook to process for activity id ", stravaActivityId)
-			// start goroutine not to keep request open for too long
-			go syncActivities(stravaActivityId)
-		}
-	}
+// newFlagSet creates a flag set for the command with the shared `-config` flag
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	configPath := flags.String("config", os.Getenv("CONFIG_FILE"), "path to a .yaml/.yml/.toml config file")
+	return flags, configPath
 }
 
-const SummarySeparator string = "---Workout Summary---"
-
-func syncActivities(stravaActivityId int64) {
-	stravaActivity, err := strava2.GetActivity(stravaActivityId)
+func loadConfig(path string) (*config.Config, error) {
+	cfg, err := config.Load(path)
 	if err != nil {
-		log.Println("Error getting strava activity ", err)
-		return
-	}
-
-	if strings.Contains(stravaActivity.Description, SummarySeparator) {
-		log.Println("Activity already contains summary")
-		return
+		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
+	return cfg, nil
+}
 
-	from := stravaActivity.StartDateLocal.Add(-1 * time.Hour)
-	to := stravaActivity.StartDateLocal.Add(time.Hour)
+// app holds the configured API clients and stores shared by commands and http handlers
+type app struct {
+	strava    *strava2.Client
+	intervals *intervals2.Client
+	history   *history.Store
+	syncer    *syncer.Syncer
+	notifier  *notify.Notifier
+	exporter  *workoutfile.Exporter
+}
 
-	intervalsActivity, err := intervals2.FindActivity(stravaActivityId, &from, &to)
+func newApp(cfg *config.Config) (*app, error) {
+	historyStore, err := history.Open(cfg.Storage.HistoryDb)
 	if err != nil {
-		log.Println("Error getting intervals activity ", err)
-		return
+		return nil, err
 	}
-	intervalsWorkout, err := intervals2.FindWorkoutForActivity(intervalsActivity)
-	if err != nil {
-		log.Println("Error getting intervals workout ", err)
-		return
+
+	notifier := notify.NewNotifier(cfg.Notifications)
+	a := &app{
+		strava:    strava2.NewClient(cfg.Strava, persistence.NewTokenStore(cfg.Storage.TokenDir), notifier),
+		intervals: intervals2.NewClient(cfg.Intervals),
+		history:   historyStore,
+		notifier:  notifier,
 	}
-	athleteSportSettings, err := intervals2.GetAthleteSportSettings(intervals2.SportTypeRun)
-	if err != nil {
-		log.Println("Error getting athleteSportSettings ", err)
-		return
+	a.exporter = workoutfile.NewExporter(a.intervals)
+	if a.syncer, err = syncer.New(cfg.Sync, a.strava, a.intervals, a.history, a.notifier); err != nil {
+		_ = historyStore.Close()
+		return nil, err
 	}
 
-	workoutSummary := intervalsWorkout.GenerateDescription(athleteSportSettings)
+	return a, nil
+}
 
-	updatableActivity := &strava2.UpdatableActivity{}
-	if stravaActivity.Description == "" {
-		updatableActivity.Description = SummarySeparator + "\n" + workoutSummary
-	} else {
-		updatableActivity.Description = stravaActivity.Description + "\n" + SummarySeparator + "\n" + workoutSummary
-	}
+// newStravaClient builds just the Strava client, for commands that don't need intervals.icu or the history store
+func newStravaClient(cfg *config.Config) *strava2.Client {
+	return strava2.NewClient(cfg.Strava, persistence.NewTokenStore(cfg.Storage.TokenDir),
+		notify.NewNotifier(cfg.Notifications))
+}
 
-	if err = strava2.UpdateActivity(stravaActivityId, updatableActivity); err != nil {
-		log.Println("Error updating strava activity ", err)
+func (a *app) close() {
+	if err := a.history.Close(); err != nil {
+		log.Println("Failed to close history store", err)
 	}
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strava-intervals-description-sync/internal/config"
+	"strava-intervals-description-sync/internal/intervals"
+	"strava-intervals-description-sync/internal/syncer"
+	"strings"
+)
+
+// runRender renders workouts from local files without any API calls, so text generation can be debugged with fixtures
+func runRender(args []string) error {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	workoutPath := flags.String("workout", "",
+		"workout JSON (an event or list of events as returned by eventsjson) or workout builder text, - for stdin")
+	settingsPath := flags.String("settings", "", "sport settings JSON, - for stdin, zones are unknown without it")
+	format := flags.String("format", intervals.RenderFormatText,
+		"renderer, one of "+strings.Join(intervals.RenderFormats, ", "))
+	zoneLabels := flags.String("zone-labels", config.ZoneLabelsNumbers, "zones as numbers, names or both")
+	poolLength := flags.Float64("pool-length", 0, "pool length of the swim in meters, e.g. 22.86 for 25 yd")
+	sportType := flags.String("type", "Run", "sport type of workouts given as workout builder text")
+	tmpl := flags.String("template", "", "text/template with .Summary and .Workout, as in sync.destinations")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *workoutPath == "" {
+		return errors.New("workout file is required")
+	}
+	if *workoutPath == "-" && *settingsPath == "-" {
+		return errors.New("only one of workout and settings can be read from stdin")
+	}
+	switch *zoneLabels {
+	case config.ZoneLabelsNumbers, config.ZoneLabelsNames, config.ZoneLabelsBoth:
+	default:
+		return fmt.Errorf("invalid zone labels %q", *zoneLabels)
+	}
+	renderer, err := intervals.NewRenderer(*format)
+	if err != nil {
+		return err
+	}
+
+	workouts, err := readWorkouts(*workoutPath, *sportType)
+	if err != nil {
+		return err
+	}
+	sportSettings := &intervals.AthleteSportSettings{}
+	if *settingsPath != "" {
+		content, err := readInput(*settingsPath)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(content, sportSettings); err != nil {
+			return fmt.Errorf("invalid sport settings: %w", err)
+		}
+	}
+
+	options := intervals.DescriptionOptions{ZoneLabels: *zoneLabels, PoolLength: float32(*poolLength)}
+	var outputs []string
+	for _, workout := range workouts {
+		output, err := renderer.Render(workout.Summary(sportSettings, options))
+		if err != nil {
+			return err
+		}
+		if *tmpl != "" {
+			if output, err = syncer.RenderTemplate(*tmpl, output, workout); err != nil {
+				return err
+			}
+		}
+		outputs = append(outputs, output)
+	}
+	fmt.Println(strings.Join(outputs, "\n\n"))
+	return nil
+}
+
+// readWorkouts reads a single event, a list of events (those without workout are skipped) or workout builder text
+func readWorkouts(path string, sportType string) ([]*intervals.Workout, error) {
+	content, err := readInput(path)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		var events []*intervals.Workout
+		if err = json.Unmarshal(trimmed, &events); err != nil {
+			return nil, fmt.Errorf("invalid workout JSON: %w", err)
+		}
+		var workouts []*intervals.Workout
+		for _, event := range events {
+			if event.WorkoutDoc != nil {
+				workouts = append(workouts, event)
+			}
+		}
+		if len(workouts) == 0 {
+			return nil, errors.New("none of the events has a workout")
+		}
+		return workouts, nil
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var workout *intervals.Workout
+		if err = json.Unmarshal(trimmed, &workout); err != nil {
+			return nil, fmt.Errorf("invalid workout JSON: %w", err)
+		}
+		return []*intervals.Workout{workout}, nil
+	default:
+		doc, err := intervals.ParseWorkoutText(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("invalid workout text:\n%w", err)
+		}
+		return []*intervals.Workout{{Type: sportType, WorkoutDoc: doc}}, nil
+	}
+}
+
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strava-intervals-description-sync/internal/config"
+	"strava-intervals-description-sync/internal/health"
+	"strava-intervals-description-sync/internal/history"
+	intervals2 "strava-intervals-description-sync/internal/intervals"
+	"strava-intervals-description-sync/internal/notify"
+	strava2 "strava-intervals-description-sync/internal/strava"
+	"strava-intervals-description-sync/internal/syncer"
+	"strava-intervals-description-sync/internal/util"
+	"strava-intervals-description-sync/internal/workoutfile"
+	"syscall"
+	"time"
+)
+
+func runServe(args []string) error {
+	flags, configPath := newFlagSet("serve")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	a, err := newApp(cfg)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	server := &http.Server{
+		Addr: cfg.ListenAddress,
+	}
+
+	http.HandleFunc(strava2.WebhookUrl, a.handleWebhookRequest)
+	http.HandleFunc(strava2.InitiateAuthenticationUrl, a.strava.HandleAuthentication)
+	http.HandleFunc(strava2.AuthenticationCallbackUrl, a.strava.HandleAuthenticationCallback)
+
+	if a.intervals.WebhooksEnabled() {
+		http.HandleFunc("POST "+intervals2.WebhookUrl, a.intervals.HandleWebhook)
+	}
+
+	checks := []health.Check{{Name: "strava_token", Run: a.strava.CheckAccessToken}}
+	// in verify mode the subscription may belong to another instance sharing the Strava app
+	ownsSubscription := cfg.Strava.WebhookMode == config.WebhookModeReplace
+	if ownsSubscription {
+		checks = append(checks, health.Check{Name: "strava_webhook_subscription", Run: a.strava.CheckWebhookSubscription})
+	}
+	checks = append(checks, health.Check{Name: "intervals_api_key", Run: a.intervals.CheckApiKey})
+	readinessChecker := health.NewChecker(checks...)
+	http.HandleFunc(health.LivenessUrl, health.HandleLiveness)
+	http.HandleFunc(health.ReadinessUrl, readinessChecker.HandleReadiness)
+
+	if cfg.Admin.Token != "" {
+		http.HandleFunc("GET "+history.ListUrl, util.RequireBearerToken(cfg.Admin.Token, a.history.HandleList))
+		http.HandleFunc("GET "+history.ActivityUrl, util.RequireBearerToken(cfg.Admin.Token, a.history.HandleActivity))
+		http.HandleFunc("POST "+syncer.UnsyncUrl, util.RequireBearerToken(cfg.Admin.Token, a.syncer.HandleUnsync))
+		http.HandleFunc("POST "+syncer.PairUrl, util.RequireBearerToken(cfg.Admin.Token, a.syncer.HandlePair))
+		http.HandleFunc("GET "+workoutfile.ExportUrl, util.RequireBearerToken(cfg.Admin.Token, a.exporter.HandleExport))
+	} else {
+		log.Println("No admin token configured, history and admin endpoints are disabled")
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("HTTP server error: %v", err)
+		}
+		log.Println("Stopped serving new connections.")
+	}()
+
+	if err = a.registerWebhookWithRetry(); err != nil {
+		log.Println("Failed to register Strava webhook, shutting down", err)
+		if err = server.Shutdown(context.Background()); err != nil {
+			log.Printf("HTTP shutdown error: %v", err)
+		}
+		a.close()
+		os.Exit(1)
+	}
+
+	if a.notifier.Enabled() && ownsSubscription {
+		go a.watchWebhookSubscription(cfg.Notifications.SubscriptionCheckInterval)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	shutdownCtx, shutdownRelease := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownRelease()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("HTTP shutdown error: %w", err)
+	}
+
+	log.Println("Stopped serving new connections.")
+	return nil
+}
+
+// webhookRegistrationRetries is how many times startup webhook registration is retried (with exponential backoff)
+// before giving up, Strava might be temporarily unavailable or our callback url not reachable yet
+const webhookRegistrationRetries = 3
+
+func (a *app) registerWebhookWithRetry() error {
+	var err error
+	for retry := 0; retry <= webhookRegistrationRetries; retry++ {
+		if err = a.strava.InitiateWebhookRegistration(); err == nil {
+			return nil
+		}
+		if retry == webhookRegistrationRetries {
+			break
+		}
+
+		retryDelay := time.Duration(1<<retry) * 5 * time.Second
+		log.Printf("Retrying webhook registration (%d/%d) in %v", retry+1, webhookRegistrationRetries, retryDelay)
+		time.Sleep(retryDelay)
+	}
+	return err
+}
+
+// watchWebhookSubscription periodically checks that the Strava webhook subscription still exists, Strava drops
+// subscriptions whose callback fails for too long and nothing gets synced after that
+func (a *app) watchWebhookSubscription(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := a.strava.CheckWebhookSubscription()
+		if errors.Is(err, strava2.ErrNoWebhookSubscription) {
+			log.Println("Strava webhook subscription is missing", err)
+			a.notifier.Notify(&notify.Notification{
+				Kind:   notify.KindWebhookSubscription,
+				Title:  "Strava webhook subscription missing",
+				Reason: fmt.Sprintf("%v, new activities won't be synced until the server is restarted", err),
+			})
+		} else if err != nil {
+			log.Println("Failed to check Strava webhook subscription", err)
+		}
+	}
+}
+
+func (a *app) handleWebhookRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodGet {
+		a.strava.HandleWebhookRegistrationRequest(w, req)
+	} else if req.Method == http.MethodPost {
+		shouldProcess, webhook := a.strava.ShouldProcessWebhook(w, req)
+		if shouldProcess {
+			log.Printf("Received %s webhook to process for activity id %d", webhook.AspectType, webhook.ObjectId)
+			// start goroutine not to keep request open for too long
+			if webhook.AspectType == strava2.WebhookAspectTypeUpdate {
+				go a.syncer.Resync(webhook.ObjectId)
+			} else {
+				go a.syncer.Sync(webhook.ObjectId)
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strava-intervals-description-sync/internal/strava"
+	"text/tabwriter"
+	"time"
+)
+
+// runSubscription manages the Strava webhook subscription, Strava allows one per app so instances sharing the app
+// manage it from here instead of replacing it on startup
+func runSubscription(args []string) error {
+	actions := map[string]func(client *strava.Client, id int) error{
+		"list":   listSubscriptions,
+		"create": createSubscription,
+		"delete": deleteSubscription,
+		"verify": verifySubscription,
+	}
+	if len(args) == 0 || actions[args[0]] == nil {
+		return errors.New("usage: subscription list|create|delete|verify [flags]")
+	}
+	action := args[0]
+
+	flags, configPath := newFlagSet("subscription " + action)
+	id := flags.Int("id", 0, "subscription id to delete, defaults to the existing subscription")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	return actions[action](newStravaClient(cfg), *id)
+}
+
+func listSubscriptions(client *strava.Client, _ int) error {
+	subs, err := client.ListSubscriptions()
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		fmt.Println("No webhook subscription")
+		return nil
+	}
+
+	callbackUrl, err := client.WebhookCallbackUrl()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tCALLBACK URL\tCREATED\tTHIS INSTANCE")
+	for _, sub := range subs {
+		thisInstance := "no"
+		if sub.CallbackUrl == callbackUrl {
+			thisInstance = "yes"
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", sub.Id, sub.CallbackUrl, sub.CreatedAt.Format(time.RFC3339),
+			thisInstance)
+	}
+	return w.Flush()
+}
+
+// createSubscription never replaces an existing subscription, it has to be deleted explicitly first
+func createSubscription(client *strava.Client, _ int) error {
+	callbackUrl, err := client.WebhookCallbackUrl()
+	if err != nil {
+		return err
+	}
+	sub, err := client.GetSubscription()
+	if err != nil {
+		return err
+	}
+	if sub != nil {
+		if sub.CallbackUrl == callbackUrl {
+			fmt.Printf("Subscription %d already points to %s\n", sub.Id, callbackUrl)
+			return nil
+		}
+		return fmt.Errorf("subscription %d points to %s, delete it first to subscribe %s", sub.Id, sub.CallbackUrl,
+			callbackUrl)
+	}
+
+	id, err := client.CreateSubscription()
+	if err != nil {
+		return fmt.Errorf("%w, the server has to be running and reachable at %s for Strava to validate it", err,
+			callbackUrl)
+	}
+	fmt.Printf("Created subscription %d for %s\n", id, callbackUrl)
+	return nil
+}
+
+func deleteSubscription(client *strava.Client, id int) error {
+	if id == 0 {
+		sub, err := client.GetSubscription()
+		if err != nil {
+			return err
+		}
+		if sub == nil {
+			fmt.Println("No webhook subscription, nothing to delete")
+			return nil
+		}
+		id = int(sub.Id)
+		fmt.Printf("Deleting subscription %d for %s\n", sub.Id, sub.CallbackUrl)
+	}
+
+	if err := client.DeleteSubscription(int32(id)); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted subscription %d\n", id)
+	return nil
+}
+
+func verifySubscription(client *strava.Client, _ int) error {
+	if err := client.CheckWebhookSubscription(); err != nil {
+		return err
+	}
+	callbackUrl, err := client.WebhookCallbackUrl()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("OK, subscription points to %s\n", callbackUrl)
+	return nil
+}
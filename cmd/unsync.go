@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strava-intervals-description-sync/internal/syncer"
+	"strings"
+	"time"
+)
+
+func runUnsync(args []string) error {
+	flags, configPath := newFlagSet("unsync")
+	activityId := flags.Int64("activity", 0, "Strava activity id to unsync")
+	from := flags.String("from", "", "unsync all activities started at or after this date (YYYY-MM-DD or RFC3339)")
+	to := flags.String("to", "", "unsync all activities started before this date (YYYY-MM-DD is inclusive, or RFC3339)")
+	dryRun := flags.Bool("dry-run", false, "only print what would change")
+	asJson := flags.Bool("json", false, "print results as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var fromTime, toTime time.Time
+	if *activityId == 0 {
+		var err error
+		if fromTime, toTime, err = syncer.ParseRange(*from, *to); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	a, err := newApp(cfg)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	var results []*syncer.UnsyncResult
+	if *activityId != 0 {
+		result, err := a.syncer.Unsync(*activityId, *dryRun)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	} else if results, err = a.syncer.UnsyncRange(fromTime, toTime, *dryRun); err != nil {
+		return err
+	}
+
+	if *asJson {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	for _, result := range results {
+		switch {
+		case result.Error != "":
+			fmt.Printf("%d: failed: %s\n", result.StravaActivityId, result.Error)
+		case !result.Changed():
+			fmt.Printf("%d: no summary, nothing to do\n", result.StravaActivityId)
+		case result.DryRun:
+			fmt.Printf("%d: would %s, new description:\n%s\n", result.StravaActivityId,
+				describeUnsync(result), indent(result.DescriptionAfter))
+			if result.NameAfter != result.NameBefore {
+				fmt.Printf("    title: %s\n", result.NameAfter)
+			}
+			fmt.Println()
+		default:
+			fmt.Printf("%d: done, %s\n", result.StravaActivityId, describeUnsync(result))
+		}
+	}
+	return nil
+}
+
+func describeUnsync(result *syncer.UnsyncResult) string {
+	var description string
+	switch result.Method {
+	case syncer.UnsyncMethodHistory:
+		description = "restore pre-sync description from history"
+	case syncer.UnsyncMethodStrip:
+		description = "strip summary"
+	}
+	if result.NameAfter == result.NameBefore {
+		return description
+	}
+	if description == "" {
+		return "restore pre-sync title"
+	}
+	return description + " and title"
+}
+
+func indent(text string) string {
+	if text == "" {
+		return "    (empty)"
+	}
+	return "    " + strings.ReplaceAll(text, "\n", "\n    ")
+}
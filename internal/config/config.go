@@ -0,0 +1,626 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+type Config struct {
+	// ListenAddress is the address the http server binds to, defaults to `:5001`
+	ListenAddress string          `yaml:"listen_address" toml:"listen_address"`
+	Strava        StravaConfig    `yaml:"strava" toml:"strava"`
+	Intervals     IntervalsConfig `yaml:"intervals" toml:"intervals"`
+	Storage       StorageConfig   `yaml:"storage" toml:"storage"`
+	Admin         AdminConfig     `yaml:"admin" toml:"admin"`
+	Sync          SyncConfig      `yaml:"sync" toml:"sync"`
+	// Notifications are sent on failures that need attention, disabled unless a webhook url or smtp host is set
+	Notifications NotificationsConfig `yaml:"notifications" toml:"notifications"`
+}
+
+type StravaConfig struct {
+	ClientId     string `yaml:"client_id" toml:"client_id"`
+	ClientSecret string `yaml:"client_secret" toml:"client_secret"`
+	// VerifyToken is sent to Strava on webhook registration and is echoed back on the subscription validation request
+	VerifyToken string `yaml:"verify_token" toml:"verify_token"`
+	// CallbackBaseUrl is the publicly reachable base url of this service, webhook and auth callback paths are appended to it
+	CallbackBaseUrl string `yaml:"callback_base_url" toml:"callback_base_url"`
+	// AthleteId is the only Strava athlete whose webhooks are processed
+	AthleteId string `yaml:"athlete_id" toml:"athlete_id"`
+	// WebhookMode is what happens with the webhook subscription on startup, `replace` creates it and replaces one with
+	// a different callback url, `verify` only logs whether it points here. Strava allows a single subscription per app,
+	// so instances sharing the app (e.g. staging) should use `verify`
+	WebhookMode string `yaml:"webhook_mode" toml:"webhook_mode"`
+}
+
+const (
+	WebhookModeReplace = "replace"
+	WebhookModeVerify  = "verify"
+)
+
+type IntervalsConfig struct {
+	AthleteId string `yaml:"athlete_id" toml:"athlete_id"`
+	ApiKey    string `yaml:"api_key" toml:"api_key"`
+	// WebhookSecret enables the intervals.icu webhook endpoint, it has to match the secret set in intervals.icu
+	// developer settings
+	WebhookSecret string `yaml:"webhook_secret" toml:"webhook_secret"`
+	// WebhookTimeout is how long a sync waits for the intervals.icu webhook before falling back to polling
+	WebhookTimeout time.Duration  `yaml:"webhook_timeout" toml:"webhook_timeout"`
+	Matching       MatchingConfig `yaml:"matching" toml:"matching"`
+	// ActivityMatching configures finding intervals.icu activities that weren't imported from Strava
+	ActivityMatching ActivityMatchingConfig `yaml:"activity_matching" toml:"activity_matching"`
+}
+
+// ActivityMatchingConfig enables matching intervals.icu activities without `strava_id` (e.g. uploaded by Garmin to both
+// services) by their attributes. All of them have to be within tolerance and only a single activity may match
+type ActivityMatchingConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// StartTolerance is the maximum difference of start times
+	StartTolerance time.Duration `yaml:"start_tolerance" toml:"start_tolerance"`
+	// ElapsedTimeTolerance and DistanceTolerance are maximum relative differences (0.05 = 5%)
+	ElapsedTimeTolerance float64 `yaml:"elapsed_time_tolerance" toml:"elapsed_time_tolerance"`
+	DistanceTolerance    float64 `yaml:"distance_tolerance" toml:"distance_tolerance"`
+}
+
+// MatchingConfig tunes scoring of planned events against an activity when intervals.icu hasn't paired them itself.
+// Every criteria scores in [0, 1] and confidence is their weighted average
+type MatchingConfig struct {
+	// MinConfidence is the minimum confidence to accept a match, lower scoring activities are skipped
+	MinConfidence float64 `yaml:"min_confidence" toml:"min_confidence"`
+	// SearchDays extends candidate events to this many days before and after the activity's day, for workouts that
+	// were moved in the calendar. Candidates further away from activity's day score lower on date
+	SearchDays int `yaml:"search_days" toml:"search_days"`
+
+	SportTypeWeight float64 `yaml:"sport_type_weight" toml:"sport_type_weight"`
+	CategoryWeight  float64 `yaml:"category_weight" toml:"category_weight"`
+	DateWeight      float64 `yaml:"date_weight" toml:"date_weight"`
+	StartTimeWeight float64 `yaml:"start_time_weight" toml:"start_time_weight"`
+	DistanceWeight  float64 `yaml:"distance_weight" toml:"distance_weight"`
+	DurationWeight  float64 `yaml:"duration_weight" toml:"duration_weight"`
+
+	// StartTimeTolerance is the difference from planned start time at which start time score drops to 0
+	StartTimeTolerance time.Duration `yaml:"start_time_tolerance" toml:"start_time_tolerance"`
+	// DistanceTolerance and DurationTolerance are relative differences (0.25 = 25%) at which their score drops to 0
+	DistanceTolerance float64 `yaml:"distance_tolerance" toml:"distance_tolerance"`
+	DurationTolerance float64 `yaml:"duration_tolerance" toml:"duration_tolerance"`
+}
+
+type StorageConfig struct {
+	// TokenDir is where Strava access and refresh tokens are stored
+	TokenDir string `yaml:"token_dir" toml:"token_dir"`
+	// HistoryDb is the path of the sqlite sync history database, defaults to `history.db` inside TokenDir
+	HistoryDb string `yaml:"history_db" toml:"history_db"`
+}
+
+type AdminConfig struct {
+	// Token protects the admin/history endpoints (sent as `Authorization: Bearer <token>`), they are disabled when empty
+	Token string `yaml:"token" toml:"token"`
+}
+
+const (
+	NotificationFormatJson    = "json"
+	NotificationFormatSlack   = "slack"
+	NotificationFormatDiscord = "discord"
+	NotificationFormatNtfy    = "ntfy"
+)
+
+type NotificationsConfig struct {
+	Webhook NotificationWebhookConfig `yaml:"webhook" toml:"webhook"`
+	Email   EmailConfig               `yaml:"email" toml:"email"`
+	// RateLimit is the minimum time between two notifications about the same problem, e.g. failures of one activity
+	RateLimit time.Duration `yaml:"rate_limit" toml:"rate_limit"`
+	// SubscriptionCheckInterval is how often the Strava webhook subscription is checked to still exist
+	SubscriptionCheckInterval time.Duration `yaml:"subscription_check_interval" toml:"subscription_check_interval"`
+}
+
+type NotificationWebhookConfig struct {
+	Url string `yaml:"url" toml:"url"`
+	// Format of the posted body, `json` (generic), `slack`, `discord` or `ntfy` (url is the topic url)
+	Format string `yaml:"format" toml:"format"`
+}
+
+type EmailConfig struct {
+	// Host of the SMTP server, STARTTLS is used if the server supports it
+	Host     string   `yaml:"host" toml:"host"`
+	Port     int      `yaml:"port" toml:"port"`
+	Username string   `yaml:"username" toml:"username"`
+	Password string   `yaml:"password" toml:"password"`
+	From     string   `yaml:"from" toml:"from"`
+	To       []string `yaml:"to" toml:"to"`
+}
+
+type SyncConfig struct {
+	Title TitleConfig `yaml:"title" toml:"title"`
+	// ActivityRules set Strava activity fields based on the matched workout, all matching rules are applied in order
+	// so later rules override earlier ones
+	ActivityRules []ActivityRule `yaml:"activity_rules" toml:"activity_rules"`
+	// GearRules assign Strava gear based on the matched workout, first matching rule wins. Gear is only changed
+	// while the activity still has athlete's default gear (or none)
+	GearRules    []GearRule         `yaml:"gear_rules" toml:"gear_rules"`
+	Destinations DestinationsConfig `yaml:"destinations" toml:"destinations"`
+	Wellness     WellnessConfig     `yaml:"wellness" toml:"wellness"`
+	Fitness      FitnessConfig      `yaml:"fitness" toml:"fitness"`
+	// ZoneLabels is how zones are shown in the summary, `numbers` (Z2), `names` (zone names from intervals.icu sport
+	// settings, e.g. Endurance) or `both` (Z2 Endurance)
+	ZoneLabels string `yaml:"zone_labels" toml:"zone_labels"`
+	// Notation is `full` (a line per step) or `compact` (single line, e.g. `15m Z2 · 5×(3m Z4 / 2m Z1) · 10m Z1`)
+	Notation string `yaml:"notation" toml:"notation"`
+	// LengthBudget is the maximum length (in characters) of a full notation summary, longer ones fall back to compact
+	// notation. 0 disables the fallback
+	LengthBudget int `yaml:"length_budget" toml:"length_budget"`
+}
+
+const (
+	NotationFull    = "full"
+	NotationCompact = "compact"
+)
+
+const (
+	ZoneLabelsNumbers = "numbers"
+	ZoneLabelsNames   = "names"
+	ZoneLabelsBoth    = "both"
+)
+
+const (
+	WellnessFieldHrv        = "hrv"
+	WellnessFieldRestingHr  = "resting_hr"
+	WellnessFieldSleepHours = "sleep_hours"
+	WellnessFieldSleepScore = "sleep_score"
+	WellnessFieldFeel       = "feel"
+)
+
+const (
+	FitnessFieldLoad      = "load"
+	FitnessFieldIntensity = "intensity"
+	FitnessFieldFitness   = "fitness"
+	FitnessFieldFatigue   = "fatigue"
+	FitnessFieldForm      = "form"
+)
+
+// FitnessConfig appends a footer with activity's training load and athlete's fitness (CTL), fatigue (ATL) and form
+// (TSB) after the activity as computed by intervals.icu
+type FitnessConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Fields are shown in the given order, any of `load`, `intensity`, `fitness`, `fatigue` and `form`
+	Fields []string `yaml:"fields" toml:"fields"`
+}
+
+// WellnessConfig appends a line with athlete's intervals.icu wellness data of the activity day to the summary
+type WellnessConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Fields are shown in the given order, any of `hrv`, `resting_hr`, `sleep_hours`, `sleep_score` and `feel`.
+	// Fields without a value for the day are left out
+	Fields []string `yaml:"fields" toml:"fields"`
+}
+
+// DestinationsConfig controls where the workout summary is written
+type DestinationsConfig struct {
+	// Strava appends the summary to Strava activity description, enabled by default
+	Strava DestinationConfig `yaml:"strava" toml:"strava"`
+	// Intervals writes the summary to the matched intervals.icu activity, disabled by default
+	Intervals DestinationConfig `yaml:"intervals" toml:"intervals"`
+}
+
+const (
+	DestinationModeDescription = "description"
+	DestinationModeMessage     = "message"
+)
+
+type DestinationConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Mode is `description` (append to activity description) or `message` (post as activity comment, intervals only)
+	Mode string `yaml:"mode" toml:"mode"`
+	// Template is a text/template executed with `.Summary` (generated summary text) and `.Workout` (intervals.Workout)
+	Template string `yaml:"template" toml:"template"`
+}
+
+// WorkoutCriteria selects workouts, all non-empty criteria have to match
+type WorkoutCriteria struct {
+	// NameRegex is matched against the workout name
+	NameRegex string `yaml:"name_regex" toml:"name_regex"`
+	// SportType is the planned intervals.icu sport type, e.g. `Run` or `TrailRun`
+	SportType string `yaml:"sport_type" toml:"sport_type"`
+	// Class is one of `race`, `intervals` (contains repeats) or `steady`
+	Class string `yaml:"class" toml:"class"`
+}
+
+type ActivityRule struct {
+	Match WorkoutCriteria `yaml:"match" toml:"match"`
+	Set   ActivityFields  `yaml:"set" toml:"set"`
+}
+
+type GearRule struct {
+	Match WorkoutCriteria `yaml:"match" toml:"match"`
+	// GearId is the Strava gear id, e.g. `g1234567` (shoes) or `b1234567` (bikes), see the `gear` command
+	GearId string `yaml:"gear_id" toml:"gear_id"`
+}
+
+// ActivityFields are the Strava activity fields a rule can set, unset fields are left as they are
+type ActivityFields struct {
+	SportType    string `yaml:"sport_type" toml:"sport_type"`
+	Commute      *bool  `yaml:"commute" toml:"commute"`
+	Trainer      *bool  `yaml:"trainer" toml:"trainer"`
+	HideFromHome *bool  `yaml:"hide_from_home" toml:"hide_from_home"`
+}
+
+// TitleConfig controls replacing Strava's default activity titles with one built from the matched workout
+type TitleConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// DefaultPattern matches titles Strava generates (e.g. "Morning Run"), only those are replaced
+	DefaultPattern string `yaml:"default_pattern" toml:"default_pattern"`
+	// Template is a text/template executed with `.Workout` (intervals.Workout) and `.Activity` (strava.Activity)
+	Template string `yaml:"template" toml:"template"`
+}
+
+// Load reads the config file at path (if path is not empty), applies environment variable overrides and validates
+// the result. Format of the file is picked by extension, `.yaml`/`.yml` or `.toml`
+//
+// Every field that has an environment variable can also be read from a file by setting `<NAME>_FILE`, which is
+// useful for docker/kubernetes secrets
+func Load(path string) (*Config, error) {
+	cfg := defaults()
+
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Storage.HistoryDb == "" && cfg.Storage.TokenDir != "" {
+		cfg.Storage.HistoryDb = filepath.Join(cfg.Storage.TokenDir, "history.db")
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func defaults() *Config {
+	return &Config{
+		ListenAddress: ":5001",
+		Strava:        StravaConfig{WebhookMode: WebhookModeReplace},
+		Intervals: IntervalsConfig{
+			WebhookTimeout: 15 * time.Minute,
+			Matching: MatchingConfig{
+				MinConfidence:      0.6,
+				SportTypeWeight:    3,
+				CategoryWeight:     2,
+				DateWeight:         2,
+				StartTimeWeight:    1,
+				DistanceWeight:     2,
+				DurationWeight:     2,
+				StartTimeTolerance: 3 * time.Hour,
+				DistanceTolerance:  0.25,
+				DurationTolerance:  0.25,
+			},
+			ActivityMatching: ActivityMatchingConfig{
+				Enabled:              true,
+				StartTolerance:       time.Minute,
+				ElapsedTimeTolerance: 0.05,
+				DistanceTolerance:    0.05,
+			},
+		},
+		Sync: SyncConfig{
+			Title: TitleConfig{
+				DefaultPattern: `^(Morning|Lunch|Afternoon|Evening|Night) .+$`,
+				Template:       "{{ .Workout.Name }}",
+			},
+			Destinations: DestinationsConfig{
+				Strava:    DestinationConfig{Enabled: true, Mode: DestinationModeDescription, Template: "{{ .Summary }}"},
+				Intervals: DestinationConfig{Mode: DestinationModeDescription, Template: "{{ .Summary }}"},
+			},
+			ZoneLabels: ZoneLabelsNumbers,
+			Notation:   NotationFull,
+			Fitness: FitnessConfig{
+				Fields: []string{FitnessFieldLoad, FitnessFieldFitness, FitnessFieldForm},
+			},
+			Wellness: WellnessConfig{
+				Fields: []string{WellnessFieldHrv, WellnessFieldRestingHr, WellnessFieldSleepHours, WellnessFieldSleepScore,
+					WellnessFieldFeel},
+			},
+		},
+		Notifications: NotificationsConfig{
+			Webhook:                   NotificationWebhookConfig{Format: NotificationFormatJson},
+			Email:                     EmailConfig{Port: 587},
+			RateLimit:                 time.Hour,
+			SubscriptionCheckInterval: time.Hour,
+		},
+	}
+}
+
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(c); err != nil {
+			return fmt.Errorf("failed to parse yaml config %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("failed to parse toml config %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown keys in toml config %s: %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("unsupported config file extension %s, expected .yaml, .yml or .toml", filepath.Ext(path))
+	}
+
+	return nil
+}
+
+type envBinding struct {
+	name   string
+	target *string
+}
+
+func (c *Config) envBindings() []envBinding {
+	return []envBinding{
+		{"LISTEN_ADDRESS", &c.ListenAddress},
+		{"STRAVA_CLIENT_ID", &c.Strava.ClientId},
+		{"STRAVA_CLIENT_SECRET", &c.Strava.ClientSecret},
+		{"STRAVA_VERIFY_TOKEN", &c.Strava.VerifyToken},
+		{"STRAVA_CALLBACK_BASE_URL", &c.Strava.CallbackBaseUrl},
+		{"STRAVA_CLIENT_ATHLETE_ID", &c.Strava.AthleteId},
+		{"STRAVA_WEBHOOK_MODE", &c.Strava.WebhookMode},
+		{"INTERVALS_ATHLETE_ID", &c.Intervals.AthleteId},
+		{"INTERVALS_API_KEY", &c.Intervals.ApiKey},
+		{"INTERVALS_WEBHOOK_SECRET", &c.Intervals.WebhookSecret},
+		{"TOKEN_STORAGE_DIR", &c.Storage.TokenDir},
+		{"HISTORY_DB_PATH", &c.Storage.HistoryDb},
+		{"ADMIN_TOKEN", &c.Admin.Token},
+		{"NOTIFICATIONS_WEBHOOK_URL", &c.Notifications.Webhook.Url},
+		{"SMTP_PASSWORD", &c.Notifications.Email.Password},
+	}
+}
+
+// applyEnv overrides config values with `<NAME>_FILE` contents or `<NAME>`, in that order of precedence
+func (c *Config) applyEnv() error {
+	for _, binding := range c.envBindings() {
+		if filePath, ok := os.LookupEnv(binding.name + "_FILE"); ok && filePath != "" {
+			data, err := os.ReadFile(filePath)
+			if err != nil {
+				return fmt.Errorf("failed to read %s_FILE: %w", binding.name, err)
+			}
+			*binding.target = strings.TrimRight(string(data), "\r\n")
+			continue
+		}
+
+		if value, ok := os.LookupEnv(binding.name); ok && value != "" {
+			*binding.target = value
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that all required values are present and well-formed, all problems are reported at once
+func (c *Config) Validate() error {
+	var errs []error
+	required := func(name, value string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", name))
+		}
+	}
+	numeric := func(name, value string) {
+		if value == "" {
+			return
+		}
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			errs = append(errs, fmt.Errorf("%s must be numeric, got %q", name, value))
+		}
+	}
+
+	required("listen_address", c.ListenAddress)
+	required("strava.client_id", c.Strava.ClientId)
+	required("strava.client_secret", c.Strava.ClientSecret)
+	required("strava.verify_token", c.Strava.VerifyToken)
+	required("strava.callback_base_url", c.Strava.CallbackBaseUrl)
+	required("strava.athlete_id", c.Strava.AthleteId)
+	numeric("strava.athlete_id", c.Strava.AthleteId)
+	if c.Strava.WebhookMode != WebhookModeReplace && c.Strava.WebhookMode != WebhookModeVerify {
+		errs = append(errs, fmt.Errorf("strava.webhook_mode: expected replace or verify, got %q", c.Strava.WebhookMode))
+	}
+	required("intervals.athlete_id", c.Intervals.AthleteId)
+	required("intervals.api_key", c.Intervals.ApiKey)
+	required("storage.token_dir", c.Storage.TokenDir)
+
+	if c.Intervals.WebhookSecret != "" && c.Intervals.WebhookTimeout <= 0 {
+		errs = append(errs, errors.New("intervals.webhook_timeout must be positive"))
+	}
+
+	if m := c.Intervals.Matching; m.MinConfidence < 0 || m.MinConfidence > 1 {
+		errs = append(errs, errors.New("intervals.matching.min_confidence must be between 0 and 1"))
+	} else if m.SportTypeWeight < 0 || m.CategoryWeight < 0 || m.DateWeight < 0 || m.StartTimeWeight < 0 ||
+		m.DistanceWeight < 0 || m.DurationWeight < 0 {
+		errs = append(errs, errors.New("intervals.matching weights must not be negative"))
+	}
+	if m := c.Intervals.ActivityMatching; m.Enabled && (m.StartTolerance < 0 || m.ElapsedTimeTolerance < 0 ||
+		m.DistanceTolerance < 0) {
+		errs = append(errs, errors.New("intervals.activity_matching tolerances must not be negative"))
+	}
+	if c.Intervals.Matching.SearchDays < 0 {
+		errs = append(errs, errors.New("intervals.matching.search_days must not be negative"))
+	}
+
+	if c.Strava.CallbackBaseUrl != "" {
+		if err := validateBaseUrl(c.Strava.CallbackBaseUrl); err != nil {
+			errs = append(errs, fmt.Errorf("strava.callback_base_url: %w", err))
+		}
+	}
+
+	if c.Sync.Title.Enabled {
+		if _, err := regexp.Compile(c.Sync.Title.DefaultPattern); err != nil {
+			errs = append(errs, fmt.Errorf("sync.title.default_pattern: %w", err))
+		}
+		if _, err := template.New("title").Parse(c.Sync.Title.Template); err != nil {
+			errs = append(errs, fmt.Errorf("sync.title.template: %w", err))
+		}
+	}
+
+	for i, rule := range c.Sync.ActivityRules {
+		name := fmt.Sprintf("sync.activity_rules[%d]", i)
+		errs = append(errs, rule.Match.validate(name+".match"))
+		if rule.Set == (ActivityFields{}) {
+			errs = append(errs, fmt.Errorf("%s.set: at least one field is required", name))
+		}
+	}
+
+	for i, rule := range c.Sync.GearRules {
+		name := fmt.Sprintf("sync.gear_rules[%d]", i)
+		errs = append(errs, rule.Match.validate(name+".match"))
+		required(name+".gear_id", rule.GearId)
+	}
+
+	errs = append(errs, c.Sync.Destinations.Strava.validate("sync.destinations.strava", false))
+	errs = append(errs, c.Sync.Destinations.Intervals.validate("sync.destinations.intervals", true))
+	switch c.Sync.ZoneLabels {
+	case ZoneLabelsNumbers, ZoneLabelsNames, ZoneLabelsBoth:
+	default:
+		errs = append(errs, fmt.Errorf("sync.zone_labels: expected numbers, names or both, got %q", c.Sync.ZoneLabels))
+	}
+	if c.Sync.Notation != NotationFull && c.Sync.Notation != NotationCompact {
+		errs = append(errs, fmt.Errorf("sync.notation: expected full or compact, got %q", c.Sync.Notation))
+	}
+	if c.Sync.LengthBudget < 0 {
+		errs = append(errs, errors.New("sync.length_budget must not be negative"))
+	}
+	errs = append(errs, c.Sync.Wellness.validate("sync.wellness"))
+	errs = append(errs, c.Sync.Fitness.validate("sync.fitness"))
+	errs = append(errs, c.Notifications.validate("notifications"))
+
+	return errors.Join(errs...)
+}
+
+func (c DestinationConfig) validate(name string, allowMessage bool) error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Mode != DestinationModeDescription && (!allowMessage || c.Mode != DestinationModeMessage) {
+		return fmt.Errorf("%s.mode: unsupported mode %q", name, c.Mode)
+	}
+	if _, err := template.New("destination").Parse(c.Template); err != nil {
+		return fmt.Errorf("%s.template: %w", name, err)
+	}
+	return nil
+}
+
+func (c FitnessConfig) validate(name string) error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.Fields) == 0 {
+		return fmt.Errorf("%s.fields: at least one field is required", name)
+	}
+	for i, field := range c.Fields {
+		switch field {
+		case FitnessFieldLoad, FitnessFieldIntensity, FitnessFieldFitness, FitnessFieldFatigue, FitnessFieldForm:
+		default:
+			return fmt.Errorf("%s.fields[%d]: unknown field %q", name, i, field)
+		}
+	}
+	return nil
+}
+
+func (c WellnessConfig) validate(name string) error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.Fields) == 0 {
+		return fmt.Errorf("%s.fields: at least one field is required", name)
+	}
+	for i, field := range c.Fields {
+		switch field {
+		case WellnessFieldHrv, WellnessFieldRestingHr, WellnessFieldSleepHours, WellnessFieldSleepScore,
+			WellnessFieldFeel:
+		default:
+			return fmt.Errorf("%s.fields[%d]: unknown field %q", name, i, field)
+		}
+	}
+	return nil
+}
+
+func (c NotificationsConfig) validate(name string) error {
+	var errs []error
+	if c.Webhook.Url != "" {
+		if err := validateBaseUrl(c.Webhook.Url); err != nil {
+			errs = append(errs, fmt.Errorf("%s.webhook.url: %w", name, err))
+		}
+		switch c.Webhook.Format {
+		case NotificationFormatJson, NotificationFormatSlack, NotificationFormatDiscord, NotificationFormatNtfy:
+		default:
+			errs = append(errs, fmt.Errorf("%s.webhook.format: expected json, slack, discord or ntfy, got %q", name,
+				c.Webhook.Format))
+		}
+	}
+	if c.Email.Host != "" {
+		if c.Email.Port <= 0 {
+			errs = append(errs, fmt.Errorf("%s.email.port must be positive", name))
+		}
+		if c.Email.From == "" {
+			errs = append(errs, fmt.Errorf("%s.email.from is required", name))
+		}
+		if len(c.Email.To) == 0 {
+			errs = append(errs, fmt.Errorf("%s.email.to is required", name))
+		}
+	}
+	if c.RateLimit < 0 {
+		errs = append(errs, fmt.Errorf("%s.rate_limit must not be negative", name))
+	}
+	if c.SubscriptionCheckInterval <= 0 {
+		errs = append(errs, fmt.Errorf("%s.subscription_check_interval must be positive", name))
+	}
+	return errors.Join(errs...)
+}
+
+func (c WorkoutCriteria) validate(name string) error {
+	if c == (WorkoutCriteria{}) {
+		return fmt.Errorf("%s: at least one criteria is required", name)
+	}
+	if _, err := regexp.Compile(c.NameRegex); err != nil {
+		return fmt.Errorf("%s.name_regex: %w", name, err)
+	}
+	switch c.Class {
+	case "", "race", "intervals", "steady":
+	default:
+		return fmt.Errorf("%s.class: expected race, intervals or steady, got %q", name, c.Class)
+	}
+	return nil
+}
+
+func validateBaseUrl(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("expected http or https url, got %q", value)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host in %q", value)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package health
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	LivenessUrl  string = "/healthz"
+	ReadinessUrl string = "/readyz"
+)
+
+// readinessCacheTtl limits how often readiness checks hit the Strava and intervals.icu APIs, orchestrators tend to
+// probe every few seconds and Strava's rate limit is only 100 requests per 15 minutes
+const readinessCacheTtl = time.Minute
+
+// Check is a single named dependency check, Run should return nil when the dependency is usable
+type Check struct {
+	Name string
+	Run  func() error
+}
+
+type checkResult struct {
+	Ok        bool      `json:"ok"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type readinessResponse struct {
+	Ready  bool                   `json:"ready"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// Checker runs readiness checks and caches their results for readinessCacheTtl
+type Checker struct {
+	checks []Check
+
+	mu        sync.Mutex
+	lastRun   time.Time
+	lastState *readinessResponse
+}
+
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{checks: checks}
+}
+
+// HandleLiveness only reports that the process is up and serving requests
+func HandleLiveness(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write([]byte("{\"status\":\"ok\"}")); err != nil {
+		log.Println("Failed to write liveness response", err)
+	}
+}
+
+// HandleReadiness runs (or reuses cached) dependency checks and responds with 200 if all of them passed
+// or 503 otherwise, the body contains details for each check
+func (c *Checker) HandleReadiness(w http.ResponseWriter, _ *http.Request) {
+	state := c.run()
+
+	w.Header().Set("Content-Type", "application/json")
+	if state.Ready {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(state); err != nil {
+		log.Println("Failed to write readiness response", err)
+	}
+}
+
+func (c *Checker) run() *readinessResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lastState != nil && time.Since(c.lastRun) < readinessCacheTtl {
+		return c.lastState
+	}
+
+	state := &readinessResponse{Ready: true, Checks: make(map[string]checkResult, len(c.checks))}
+	for _, check := range c.checks {
+		result := checkResult{Ok: true, CheckedAt: time.Now()}
+		if err := check.Run(); err != nil {
+			log.Printf("Readiness check %s failed: %v", check.Name, err)
+			result.Ok = false
+			result.Error = err.Error()
+			state.Ready = false
+		}
+		state.Checks[check.Name] = result
+	}
+
+	c.lastRun = time.Now()
+	c.lastState = state
+	return state
+}
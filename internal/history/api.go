@@ -0,0 +1,86 @@
+package history
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	ListUrl     string = "/history"
+	ActivityUrl string = "/history/{activityId}"
+)
+
+const defaultListLimit = 50
+
+// HandleList responds with records filtered by optional `status`, `from`, `to` (YYYY-MM-DD or RFC3339) and
+// `limit` query params
+func (s *Store) HandleList(w http.ResponseWriter, req *http.Request) {
+	params := req.URL.Query()
+	q := Query{Status: Status(params.Get("status")), Limit: defaultListLimit}
+
+	var err error
+	if q.From, err = ParseTime(params.Get("from")); err != nil {
+		http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.To, err = ParseTime(params.Get("to")); err != nil {
+		http.Error(w, "invalid to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	records, err := s.List(q)
+	if err != nil {
+		log.Println("Failed to list history", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeJson(w, records)
+}
+
+// HandleActivity responds with the full audit trail of a single Strava activity
+func (s *Store) HandleActivity(w http.ResponseWriter, req *http.Request) {
+	activityId, err := strconv.ParseInt(req.PathValue("activityId"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid activity id", http.StatusBadRequest)
+		return
+	}
+
+	records, err := s.List(Query{StravaActivityId: activityId})
+	if err != nil {
+		log.Println("Failed to list activity history", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(records) == 0 {
+		http.Error(w, "no history for activity", http.StatusNotFound)
+		return
+	}
+	writeJson(w, records)
+}
+
+// ParseTime accepts either a date (YYYY-MM-DD, UTC midnight) or an RFC3339 timestamp, empty value results in zero time
+func ParseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func writeJson(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Println("Failed to write response", err)
+	}
+}
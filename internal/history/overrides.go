@@ -0,0 +1,48 @@
+package history
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Override is a manual pairing of a Strava activity set via admin endpoint, it takes precedence over automatic matching
+type Override struct {
+	StravaActivityId int64 `json:"strava_activity_id"`
+	// EventId is the intervals.icu event to use as the activity's workout
+	EventId int `json:"event_id,omitempty"`
+	// NoSync excludes the activity from syncing
+	NoSync    bool      `json:"no_sync,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SetOverride replaces the activity's override
+func (s *Store) SetOverride(o *Override) error {
+	_, err := s.db.Exec(`INSERT INTO overrides (strava_activity_id, event_id, no_sync, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (strava_activity_id) DO UPDATE SET event_id = excluded.event_id, no_sync = excluded.no_sync,
+		created_at = excluded.created_at`,
+		o.StravaActivityId, o.EventId, o.NoSync, o.CreatedAt.UnixMilli())
+	return err
+}
+
+func (s *Store) DeleteOverride(stravaActivityId int64) error {
+	_, err := s.db.Exec("DELETE FROM overrides WHERE strava_activity_id = ?", stravaActivityId)
+	return err
+}
+
+// GetOverride returns the activity's override or nil if there is none
+func (s *Store) GetOverride(stravaActivityId int64) (*Override, error) {
+	o := &Override{StravaActivityId: stravaActivityId}
+	var createdAt int64
+	err := s.db.QueryRow("SELECT event_id, no_sync, created_at FROM overrides WHERE strava_activity_id = ?",
+		stravaActivityId).Scan(&o.EventId, &o.NoSync, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	o.CreatedAt = time.UnixMilli(createdAt).UTC()
+	return o, nil
+}
@@ -0,0 +1,234 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	_ "modernc.org/sqlite"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusSynced   Status = "synced"
+	StatusUnsynced Status = "unsynced"
+	StatusSkipped  Status = "skipped"
+	StatusFailed   Status = "failed"
+)
+
+type Action string
+
+const (
+	ActionSync   Action = "sync"
+	ActionUnsync Action = "unsync"
+)
+
+// Record is a single entry of an activity's audit trail, one is written for every sync attempt
+type Record struct {
+	Id               int64  `json:"id"`
+	StravaActivityId int64  `json:"strava_activity_id"`
+	Action           Action `json:"action"`
+	Status           Status `json:"status"`
+	// IntervalsActivityId is empty if the sync failed before the intervals.icu activity was found
+	IntervalsActivityId string `json:"intervals_activity_id,omitempty"`
+	// ActivityMatchStrategy tells how the intervals.icu activity was found, see intervals.ActivityMatchStrategy
+	ActivityMatchStrategy string `json:"activity_match_strategy,omitempty"`
+	// WorkoutId is the intervals.icu event id of the matched workout, 0 if none was matched
+	WorkoutId int `json:"workout_id,omitempty"`
+	// WorkoutMatchStrategy describes how WorkoutId was picked, see intervals.MatchStrategy
+	WorkoutMatchStrategy string `json:"workout_match_strategy,omitempty"`
+	// WorkoutMatchConfidence is in [0, 1], see intervals.WorkoutMatch
+	WorkoutMatchConfidence float64 `json:"workout_match_confidence,omitempty"`
+	DescriptionBefore      string  `json:"description_before"`
+	DescriptionAfter       string  `json:"description_after"`
+	// NameBefore and NameAfter are only different if the activity title was replaced
+	NameBefore string `json:"name_before,omitempty"`
+	NameAfter  string `json:"name_after,omitempty"`
+	// IntervalsMessagePosted is set when this sync posted the summary as intervals.icu activity message, messages can't
+	// be replaced so later syncs of the activity don't post again
+	IntervalsMessagePosted bool      `json:"intervals_message_posted,omitempty"`
+	StartedAt              time.Time `json:"started_at"`
+	FinishedAt             time.Time `json:"finished_at"`
+	Error                  string    `json:"error,omitempty"`
+}
+
+// Query filters records returned by Store.List, zero values mean no filter
+type Query struct {
+	StravaActivityId int64
+	Status           Status
+	From             time.Time
+	To               time.Time
+	Limit            int
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+// migrations are applied in order and tracked via sqlite `user_version`, only ever append to this list
+var migrations = []string{
+	`CREATE TABLE records (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		strava_activity_id INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		status TEXT NOT NULL,
+		intervals_activity_id TEXT NOT NULL DEFAULT '',
+		workout_id INTEGER NOT NULL DEFAULT 0,
+		workout_match_strategy TEXT NOT NULL DEFAULT '',
+		description_before TEXT NOT NULL DEFAULT '',
+		description_after TEXT NOT NULL DEFAULT '',
+		started_at INTEGER NOT NULL,
+		finished_at INTEGER NOT NULL,
+		error TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX records_strava_activity_id ON records (strava_activity_id);
+	CREATE INDEX records_started_at ON records (started_at);`,
+	`ALTER TABLE records ADD COLUMN name_before TEXT NOT NULL DEFAULT '';
+	ALTER TABLE records ADD COLUMN name_after TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE records ADD COLUMN workout_match_confidence REAL NOT NULL DEFAULT 0;`,
+	`CREATE TABLE overrides (
+		strava_activity_id INTEGER PRIMARY KEY,
+		event_id INTEGER NOT NULL DEFAULT 0,
+		no_sync INTEGER NOT NULL DEFAULT 0,
+		created_at INTEGER NOT NULL
+	);`,
+	`ALTER TABLE records ADD COLUMN activity_match_strategy TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE records ADD COLUMN intervals_message_posted INTEGER NOT NULL DEFAULT 0;`,
+}
+
+// Open opens (creating if needed) the sqlite database at path and applies pending migrations. WAL mode is used so the
+// `history` CLI command can read while the server is writing
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	store := &Store{db: db}
+	if err = store.migrate(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) migrate() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(migrations[i]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("history migration %d failed: %w", i+1, err)
+		}
+		if _, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Add inserts the record and sets its Id
+func (s *Store) Add(r *Record) error {
+	result, err := s.db.Exec(`INSERT INTO records (strava_activity_id, action, status, intervals_activity_id,
+		activity_match_strategy, workout_id,
+		workout_match_strategy, workout_match_confidence, description_before, description_after, name_before, name_after,
+		intervals_message_posted, started_at, finished_at, error) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.StravaActivityId, r.Action, r.Status, r.IntervalsActivityId, r.ActivityMatchStrategy, r.WorkoutId, r.WorkoutMatchStrategy,
+		r.WorkoutMatchConfidence, r.DescriptionBefore, r.DescriptionAfter, r.NameBefore, r.NameAfter, r.IntervalsMessagePosted, r.StartedAt.UnixMilli(),
+		r.FinishedAt.UnixMilli(), r.Error)
+	if err != nil {
+		return err
+	}
+
+	r.Id, err = result.LastInsertId()
+	return err
+}
+
+// List returns records matching the query, newest first
+func (s *Store) List(q Query) ([]*Record, error) {
+	query := `SELECT id, strava_activity_id, action, status, intervals_activity_id, activity_match_strategy, workout_id,
+		workout_match_strategy,
+		workout_match_confidence, description_before, description_after, name_before, name_after, intervals_message_posted, started_at,
+		finished_at, error
+		FROM records WHERE 1 = 1`
+	var args []any
+
+	if q.StravaActivityId != 0 {
+		query += " AND strava_activity_id = ?"
+		args = append(args, q.StravaActivityId)
+	}
+	if q.Status != "" {
+		query += " AND status = ?"
+		args = append(args, q.Status)
+	}
+	if !q.From.IsZero() {
+		query += " AND started_at >= ?"
+		args = append(args, q.From.UnixMilli())
+	}
+	if !q.To.IsZero() {
+		query += " AND started_at < ?"
+		args = append(args, q.To.UnixMilli())
+	}
+	query += " ORDER BY started_at DESC, id DESC"
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	records := make([]*Record, 0)
+	for rows.Next() {
+		var r Record
+		var startedAt, finishedAt int64
+		if err = rows.Scan(&r.Id, &r.StravaActivityId, &r.Action, &r.Status, &r.IntervalsActivityId, &r.ActivityMatchStrategy, &r.WorkoutId,
+			&r.WorkoutMatchStrategy, &r.WorkoutMatchConfidence, &r.DescriptionBefore, &r.DescriptionAfter, &r.NameBefore, &r.NameAfter,
+			&r.IntervalsMessagePosted, &startedAt, &finishedAt, &r.Error); err != nil {
+			return nil, err
+		}
+		r.StartedAt = time.UnixMilli(startedAt).UTC()
+		r.FinishedAt = time.UnixMilli(finishedAt).UTC()
+		records = append(records, &r)
+	}
+
+	return records, rows.Err()
+}
+
+// Latest returns the newest record of the activity matching status (any status if empty), or nil if there is none
+func (s *Store) Latest(stravaActivityId int64, status Status) (*Record, error) {
+	records, err := s.List(Query{StravaActivityId: stravaActivityId, Status: status, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return records[0], nil
+}
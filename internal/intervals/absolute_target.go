@@ -0,0 +1,68 @@
+package intervals
+
+// AbsoluteTarget is a step target in absolute values, bpm for heart rate, m/s for pace and watts for power. Start and
+// End are equal for single value targets, for ramps the target goes from Start to End
+type AbsoluteTarget struct {
+	Kind  TargetKind
+	Start float32
+	End   float32
+}
+
+// AbsoluteTarget resolves the step's target using sport settings, zones resolve to their bounds. ok is false for steps
+// without a target or when units aren't supported or sport settings lack the values needed
+func (w *WorkoutStep) AbsoluteTarget(sportSettings *AthleteSportSettings) (*AbsoluteTarget, bool) {
+	if sportSettings == nil {
+		sportSettings = &AthleteSportSettings{}
+	}
+
+	switch {
+	case w.HeartRate != nil:
+		if w.HeartRate.Units == "hr_zone" {
+			upperBounds := make([]float32, len(sportSettings.HeartRateZones))
+			for i, hrZoneUpperValue := range sportSettings.HeartRateZones {
+				upperBounds[i] = float32(hrZoneUpperValue)
+			}
+			return zoneTarget(TargetHeartRate, w.HeartRate, upperBounds, 1)
+		}
+		return convertedTarget(TargetHeartRate, w.HeartRate, heartRateConverter(w.HeartRate.Units, sportSettings))
+	case w.Pace != nil:
+		if w.Pace.Units == "pace_zone" {
+			return zoneTarget(TargetPace, w.Pace, sportSettings.PaceZones, sportSettings.ThresholdPace/100)
+		}
+		return convertedTarget(TargetPace, w.Pace, speedConverter(w.Pace.Units, sportSettings))
+	case w.Power != nil:
+		if w.Power.Units == "power_zone" {
+			return zoneTarget(TargetPower, w.Power, sportSettings.PowerZones, float32(sportSettings.Ftp)/100)
+		}
+		return convertedTarget(TargetPower, w.Power, wattsConverter(w.Power.Units, sportSettings))
+	default:
+		return nil, false
+	}
+}
+
+func convertedTarget(kind TargetKind, unit *WorkoutStepUnit, convert func(value float32) float32) (*AbsoluteTarget, bool) {
+	start, end, _ := unit.bounds()
+	if convert == nil || start <= 0 || end <= 0 {
+		return nil, false
+	}
+	return &AbsoluteTarget{Kind: kind, Start: convert(start), End: convert(end)}, true
+}
+
+// zoneTarget resolves zone (or zone range) to the lower bound of the first and upper bound of the last zone, bounds
+// are multiplied by scale, e.g. percentage of threshold to absolute value
+func zoneTarget(kind TargetKind, unit *WorkoutStepUnit, upperBounds []float32, scale float32) (*AbsoluteTarget, bool) {
+	start, end, _ := unit.bounds()
+	startZone, endZone := int(start), int(end)
+	if scale <= 0 || startZone < 1 || endZone < 1 || startZone > len(upperBounds) || endZone > len(upperBounds) {
+		return nil, false
+	}
+	if startZone > endZone {
+		startZone, endZone = endZone, startZone
+	}
+
+	var lower float32
+	if startZone > 1 {
+		lower = upperBounds[startZone-2]
+	}
+	return &AbsoluteTarget{Kind: kind, Start: lower * scale, End: upperBounds[endZone-1] * scale}, true
+}
@@ -0,0 +1,90 @@
+package intervals
+
+import (
+	"math"
+	"strava-intervals-description-sync/internal/config"
+	"strconv"
+	"time"
+)
+
+// ActivityQuery describes the Strava activity whose intervals.icu copy is searched for
+type ActivityQuery struct {
+	StravaId int64
+	// Start is the actual start instant of the activity
+	Start time.Time
+	// ElapsedTime in seconds
+	ElapsedTime float32
+	// Distance in meters
+	Distance  float32
+	SportType string
+}
+
+// ActivityMatchStrategy describes how an intervals.icu activity was matched to the Strava activity
+type ActivityMatchStrategy string
+
+const (
+	// ActivityMatchStrategyStravaId means intervals.icu activity was imported from Strava and has its id
+	ActivityMatchStrategyStravaId ActivityMatchStrategy = "strava_id"
+	// ActivityMatchStrategyAttributes means start time, elapsed time, distance and sport type matched, e.g. for
+	// activities uploaded by Garmin directly to both services
+	ActivityMatchStrategyAttributes ActivityMatchStrategy = "attributes"
+)
+
+type ActivityMatch struct {
+	Activity *Activity
+	Strategy ActivityMatchStrategy
+}
+
+// findActivity returns the activity with query's Strava id, or otherwise the single one matching its attributes.
+// If more than one activity matches attributes, none is picked as it can't be told which one is the right one
+func findActivity(cfg config.ActivityMatchingConfig, query *ActivityQuery, activities []*Activity) *ActivityMatch {
+	var attributeMatches []*Activity
+	for _, activity := range activities {
+		strategy, ok := matchActivity(cfg, query, activity)
+		if !ok {
+			continue
+		}
+		if strategy == ActivityMatchStrategyStravaId {
+			return &ActivityMatch{Activity: activity, Strategy: strategy}
+		}
+		attributeMatches = append(attributeMatches, activity)
+	}
+
+	if len(attributeMatches) == 1 {
+		return &ActivityMatch{Activity: attributeMatches[0], Strategy: ActivityMatchStrategyAttributes}
+	}
+	return nil
+}
+
+func matchActivity(cfg config.ActivityMatchingConfig, query *ActivityQuery, activity *Activity) (ActivityMatchStrategy, bool) {
+	if activity.StravaId == strconv.FormatInt(query.StravaId, 10) {
+		return ActivityMatchStrategyStravaId, true
+	}
+	// activity of another Strava activity, or attribute matching is disabled
+	if activity.StravaId != "" || !cfg.Enabled {
+		return "", false
+	}
+
+	if query.Start.IsZero() || math.Abs(activity.StartDate.Sub(query.Start).Seconds()) > cfg.StartTolerance.Seconds() {
+		return "", false
+	}
+	if query.SportType != "" && activity.Type != "" && sportFamily(query.SportType) != sportFamily(activity.Type) {
+		return "", false
+	}
+	if !withinRelativeTolerance(query.ElapsedTime, activity.ElapsedTime, cfg.ElapsedTimeTolerance) {
+		return "", false
+	}
+	if !withinRelativeTolerance(query.Distance, activity.Distance, cfg.DistanceTolerance) {
+		return "", false
+	}
+
+	return ActivityMatchStrategyAttributes, true
+}
+
+// withinRelativeTolerance is true if either value is unknown (0) or they differ by at most tolerance of expected
+func withinRelativeTolerance(expected float32, actual float32, tolerance float64) bool {
+	if expected <= 0 || actual <= 0 {
+		return true
+	}
+	return math.Abs(float64(actual-expected)) <= float64(expected)*tolerance
+}
@@ -7,30 +7,56 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"math"
 	"net/http"
 	"os"
+	"strava-intervals-description-sync/internal/config"
 	"strava-intervals-description-sync/internal/util"
-	"strconv"
 	"time"
 )
 
-func FindActivity(stravaActivityId int64, from *time.Time, to *time.Time) (*Activity, error) {
+// Client talks to the intervals.icu API on behalf of the configured athlete
+type Client struct {
+	cfg config.IntervalsConfig
+	// correlator is nil unless intervals.icu webhooks are configured
+	correlator *Correlator
+}
+
+func NewClient(cfg config.IntervalsConfig) *Client {
+	c := &Client{cfg: cfg}
+	if cfg.WebhookSecret != "" {
+		c.correlator = NewCorrelator(cfg.ActivityMatching)
+	}
+	return c
+}
+
+// WebhooksEnabled reports whether HandleWebhook should be served
+func (c *Client) WebhooksEnabled() bool {
+	return c.correlator != nil
+}
+
+// FindActivity polls intervals.icu activities in [from, to] (wall-clock of their location) with exponential backoff
+// until one matching query shows up
+func (c *Client) FindActivity(query *ActivityQuery, from *time.Time, to *time.Time) (*ActivityMatch, error) {
 	findActivityFunc := func() (*http.Response, error) {
 		client := &http.Client{}
 
 		fromFmt := from.Format("2006-01-02T15:04:05")
 		toFmt := to.Format("2006-01-02T15:04:05")
 		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://intervals.icu/api/v1/athlete/%s/activities?oldest=%s&newest=%s",
-			os.Getenv("INTERVALS_ATHLETE_ID"), fromFmt, toFmt), nil)
+			c.cfg.AthleteId, fromFmt, toFmt), nil)
 		if err != nil {
 			return nil, err
 		}
-		req.SetBasicAuth("API_KEY", os.Getenv("INTERVALS_API_KEY"))
+		req.SetBasicAuth("API_KEY", c.cfg.ApiKey)
 		return client.Do(req)
 	}
 
 	shouldRetryFunc := func(resp *http.Response, err error) bool {
+		if err != nil {
+			log.Println("Failed to fetch intervals activities:", err)
+			return true
+		}
+
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 
 			b, err := io.ReadAll(resp.Body)
@@ -44,13 +70,7 @@ func FindActivity(stravaActivityId int64, from *time.Time, to *time.Time) (*Acti
 				return false
 			}
 
-			for _, activity := range activities {
-				if activity.StravaId == strconv.Itoa(int(stravaActivityId)) {
-					return false
-				}
-			}
-
-			return true
+			return findActivity(c.cfg.ActivityMatching, query, activities) == nil
 		}
 
 		log.Println("Unexpected status code:", resp.StatusCode)
@@ -73,25 +93,57 @@ func FindActivity(stravaActivityId int64, from *time.Time, to *time.Time) (*Acti
 
 	_ = json.NewEncoder(os.Stdout).Encode(activities)
 
-	for _, activity := range activities {
-		if activity.StravaId == strconv.Itoa(int(stravaActivityId)) {
-			return activity, nil
-		}
+	if match := findActivity(c.cfg.ActivityMatching, query, activities); match != nil {
+		return match, nil
 	}
 
 	return nil, errors.New("couldn't find matching activity")
 }
 
+// GetAthlete fetches the configured athlete's profile
+func (c *Client) GetAthlete() (*Athlete, error) {
+	client := &http.Client{}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://intervals.icu/api/v1/athlete/%s",
+		c.cfg.AthleteId), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth("API_KEY", c.cfg.ApiKey)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status code fetching intervals athlete: %d", resp.StatusCode)
+	}
+
+	var athlete *Athlete
+	if err = json.NewDecoder(resp.Body).Decode(&athlete); err != nil {
+		return nil, err
+	}
+
+	return athlete, nil
+}
+
+// CheckApiKey verifies that the configured api key is accepted by intervals.icu for the configured athlete
+func (c *Client) CheckApiKey() error {
+	_, err := c.GetAthlete()
+	return err
+}
+
 // GetAthleteSportSettings fetches 'setting' like hr/pace zones from intervals.icu
-func GetAthleteSportSettings(sportType SportType) (*AthleteSportSettings, error) {
+func (c *Client) GetAthleteSportSettings(sportType SportType) (*AthleteSportSettings, error) {
 	client := &http.Client{}
 
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://intervals.icu/api/v1/athlete/%s/sport-settings/%s",
-		os.Getenv("INTERVALS_ATHLETE_ID"), sportType), nil)
+		c.cfg.AthleteId, sportType), nil)
 	if err != nil {
 		return nil, err
 	}
-	req.SetBasicAuth("API_KEY", os.Getenv("INTERVALS_API_KEY"))
+	req.SetBasicAuth("API_KEY", c.cfg.ApiKey)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -115,18 +167,40 @@ func GetAthleteSportSettings(sportType SportType) (*AthleteSportSettings, error)
 	return athleteSettings, nil
 }
 
-func FindWorkoutForActivity(intervalsActivity *Activity) (*Workout, error) {
-	activityYear, activityMonth, activityDay := intervalsActivity.StartDate.Date()
-	workoutFrom := time.Date(activityYear, activityMonth, activityDay, 0, 0, 0, 0, time.UTC)
-	workoutTo := time.Date(activityYear, activityMonth, activityDay+1, 0, 0, 0, 0, time.UTC)
+// GetWorkoutSportSettings fetches sport settings of the workout's sport. Athlete's resting hr is fetched as well only
+// when the workout has `%hrr` targets, it isn't part of sport settings
+func (c *Client) GetWorkoutSportSettings(workout *Workout) (*AthleteSportSettings, error) {
+	athleteSettings, err := c.GetAthleteSportSettings(SportSettingsType(workout.Type))
+	if err != nil {
+		return nil, err
+	}
+
+	if workout.usesHeartRateUnits("%hrr") {
+		athlete, err := c.GetAthlete()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get intervals athlete resting heart rate: %w", err)
+		}
+		athleteSettings.RestingHeartRate = athlete.RestingHeartRate
+	}
+
+	return athleteSettings, nil
+}
+
+// FindWorkoutForActivity matches a planned workout to the activity. Candidates are events of the activity's day in loc
+// (athlete's timezone), extended by configured search days on both sides for workouts moved in the calendar
+func (c *Client) FindWorkoutForActivity(intervalsActivity *Activity, loc *time.Location) (*WorkoutMatch, error) {
+	activityYear, activityMonth, activityDay := intervalsActivity.StartDate.In(loc).Date()
+	searchDays := c.cfg.Matching.SearchDays
+	workoutFrom := time.Date(activityYear, activityMonth, activityDay-searchDays, 0, 0, 0, 0, loc)
+	workoutTo := time.Date(activityYear, activityMonth, activityDay+searchDays, 23, 59, 59, 0, loc)
 
 	client := &http.Client{}
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://intervals.icu/api/v1/athlete/%s/eventsjson?oldest=%s&newest=%s",
-		os.Getenv("INTERVALS_ATHLETE_ID"), workoutFrom.Format("2006-01-02T15:04:05"), workoutTo.Format("2006-01-02T15:04:05")), nil)
+		c.cfg.AthleteId, workoutFrom.Format("2006-01-02T15:04:05"), workoutTo.Format("2006-01-02T15:04:05")), nil)
 	if err != nil {
 		return nil, err
 	}
-	req.SetBasicAuth("API_KEY", os.Getenv("INTERVALS_API_KEY"))
+	req.SetBasicAuth("API_KEY", c.cfg.ApiKey)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -151,20 +225,81 @@ func FindWorkoutForActivity(intervalsActivity *Activity) (*Workout, error) {
 
 	_ = json.NewEncoder(os.Stdout).Encode(workouts)
 
-	for _, workout := range workouts {
-		if workout.Id == intervalsActivity.PairedEventId {
-			return workout, nil
-		}
+	return matchWorkout(c.cfg.Matching, intervalsActivity, workouts)
+}
+
+// GetEvent fetches a single calendar event (planned workout) by id
+func (c *Client) GetEvent(id int) (*Workout, error) {
+	client := &http.Client{}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://intervals.icu/api/v1/athlete/%s/events/%d",
+		c.cfg.AthleteId, id), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth("API_KEY", c.cfg.ApiKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.Println("Received unexpected status code fetching intervals event", resp.StatusCode)
+		return nil, fmt.Errorf("unexpected status code fetching intervals event %d: %d", id, resp.StatusCode)
+	}
+
+	var workout *Workout
+	if err = json.NewDecoder(resp.Body).Decode(&workout); err != nil {
+		return nil, err
+	}
+
+	return workout, nil
+}
+
+// UpdateActivity updates fields of the intervals.icu activity
+func (c *Client) UpdateActivity(id string, activity *UpdatableActivity) error {
+	jsonBody, err := json.Marshal(activity)
+	if err != nil {
+		return err
 	}
 
-	// If workout has not auto paired with activity in intervals, then let's try finding one whose
-	// duration or distance is within 5% of workout's duration or distance
-	for _, workout := range workouts {
-		if math.Abs(float64(workout.WorkoutDoc.Distance)-float64(intervalsActivity.Distance)) < float64(workout.WorkoutDoc.Distance)*0.05 ||
-			math.Abs(float64(workout.WorkoutDoc.Duration)-float64(intervalsActivity.MovingTime)) < float64(workout.WorkoutDoc.Duration)*0.05 {
-			return workout, nil
+	return c.sendJson(http.MethodPut, fmt.Sprintf("https://intervals.icu/api/v1/activity/%s", id), jsonBody)
+}
+
+// AddActivityMessage posts a comment to the intervals.icu activity
+func (c *Client) AddActivityMessage(id string, content string) error {
+	jsonBody, err := json.Marshal(&activityMessage{Content: content})
+	if err != nil {
+		return err
+	}
+
+	return c.sendJson(http.MethodPost, fmt.Sprintf("https://intervals.icu/api/v1/activity/%s/messages", id), jsonBody)
+}
+
+func (c *Client) sendJson(method string, url string, jsonBody []byte) error {
+	client := &http.Client{}
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.SetBasicAuth("API_KEY", c.cfg.ApiKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.Println("Received unexpected status code", method, url, resp.StatusCode)
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err == nil {
+			log.Println(string(bodyBytes))
 		}
+		return fmt.Errorf("unexpected status code %d from intervals.icu", resp.StatusCode)
 	}
 
-	return nil, errors.New("couldn't find workout for activity")
+	return nil
 }
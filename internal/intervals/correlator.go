@@ -0,0 +1,119 @@
+package intervals
+
+import (
+	"context"
+	"strava-intervals-description-sync/internal/config"
+	"sync"
+	"time"
+)
+
+// recentActivityTtl is how long activities received via webhook are kept for Strava webhooks that arrive later
+const recentActivityTtl = time.Hour
+
+// Correlator pairs activities received via intervals.icu webhooks with syncs waiting for them. Either side can
+// arrive first, activities are kept for recentActivityTtl in case the Strava webhook is late
+type Correlator struct {
+	cfg config.ActivityMatchingConfig
+
+	mu      sync.Mutex
+	waiters []*waiter
+	recent  []recentActivity
+}
+
+type waiter struct {
+	query *ActivityQuery
+	ch    chan *ActivityMatch
+}
+
+type recentActivity struct {
+	activity   *Activity
+	receivedAt time.Time
+}
+
+func NewCorrelator(cfg config.ActivityMatchingConfig) *Correlator {
+	return &Correlator{cfg: cfg}
+}
+
+// Publish hands activity to syncs waiting for it, and keeps it for ones that start later. An activity published again
+// (e.g. re-analyzed after an edit) replaces its earlier version. Waiters are matched against all recent activities,
+// so an activity that is ambiguous with another one isn't handed out
+func (c *Correlator) Publish(activity *Activity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pruneRecent()
+	published := recentActivity{activity: activity, receivedAt: time.Now()}
+	replaced := false
+	for i, recent := range c.recent {
+		if recent.activity.Id == activity.Id {
+			c.recent[i] = published
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		c.recent = append(c.recent, published)
+	}
+
+	recentActivities := c.recentActivities()
+	remaining := c.waiters[:0]
+	for _, w := range c.waiters {
+		if match := findActivity(c.cfg, w.query, recentActivities); match != nil {
+			w.ch <- match
+		} else {
+			remaining = append(remaining, w)
+		}
+	}
+	c.waiters = remaining
+}
+
+// Wait blocks until an activity matching query is published or ctx is done
+func (c *Correlator) Wait(ctx context.Context, query *ActivityQuery) (*ActivityMatch, error) {
+	c.mu.Lock()
+	if match := findActivity(c.cfg, query, c.recentActivities()); match != nil {
+		c.mu.Unlock()
+		return match, nil
+	}
+	// buffered so Publish never blocks on a waiter that has given up
+	w := &waiter{query: query, ch: make(chan *ActivityMatch, 1)}
+	c.waiters = append(c.waiters, w)
+	c.mu.Unlock()
+
+	select {
+	case match := <-w.ch:
+		return match, nil
+	case <-ctx.Done():
+		c.removeWaiter(w)
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Correlator) removeWaiter(w *waiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, waiter := range c.waiters {
+		if waiter == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *Correlator) recentActivities() []*Activity {
+	activities := make([]*Activity, 0, len(c.recent))
+	for _, recent := range c.recent {
+		activities = append(activities, recent.activity)
+	}
+	return activities
+}
+
+func (c *Correlator) pruneRecent() {
+	remaining := c.recent[:0]
+	for _, recent := range c.recent {
+		if time.Since(recent.receivedAt) <= recentActivityTtl {
+			remaining = append(remaining, recent)
+		}
+	}
+	c.recent = remaining
+}
@@ -0,0 +1,115 @@
+package intervals
+
+import (
+	"context"
+	"errors"
+	"strava-intervals-description-sync/internal/config"
+	"testing"
+	"time"
+)
+
+func TestCorrelator(t *testing.T) {
+	start := time.Date(2024, 5, 1, 7, 30, 0, 0, time.UTC)
+	query := &ActivityQuery{StravaId: 42, Start: start, ElapsedTime: 3600, Distance: 10000, SportType: "Run"}
+	byStravaId := func(id string, description string) *Activity {
+		return &Activity{Id: id, StravaId: "42", Description: description}
+	}
+	byAttributes := func(id string) *Activity {
+		return &Activity{Id: id, Type: "Run", StartDate: start.Add(10 * time.Second), ElapsedTime: 3610, Distance: 10020}
+	}
+
+	tests := []struct {
+		name string
+		// before are published before the sync starts waiting, after once it waits
+		before []*Activity
+		after  []*Activity
+		// wantId and wantDescription are empty if waiting should time out
+		wantId          string
+		wantDescription string
+	}{
+		{
+			name:   "published before wait",
+			before: []*Activity{byStravaId("i1", "")},
+			wantId: "i1",
+		},
+		{
+			name:   "published after wait",
+			after:  []*Activity{byStravaId("i1", "")},
+			wantId: "i1",
+		},
+		{
+			name:            "published again replaces earlier version",
+			before:          []*Activity{byStravaId("i1", "first"), byStravaId("i1", "second")},
+			wantId:          "i1",
+			wantDescription: "second",
+		},
+		{
+			name:   "single attribute match",
+			after:  []*Activity{byAttributes("i1")},
+			wantId: "i1",
+		},
+		{
+			name:   "ambiguous attribute matches",
+			before: []*Activity{byAttributes("i1"), byAttributes("i2")},
+		},
+		{
+			name:   "strava id wins over attribute matches",
+			before: []*Activity{byAttributes("i1"), byAttributes("i2")},
+			after:  []*Activity{byStravaId("i3", "")},
+			wantId: "i3",
+		},
+		{
+			name:   "other strava activity",
+			before: []*Activity{{Id: "i1", StravaId: "43"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCorrelator(config.ActivityMatchingConfig{Enabled: true, StartTolerance: time.Minute,
+				ElapsedTimeTolerance: 0.05, DistanceTolerance: 0.05})
+			for _, activity := range tt.before {
+				c.Publish(activity)
+			}
+			if len(tt.after) > 0 {
+				go func() {
+					waitForWaiter(c)
+					for _, activity := range tt.after {
+						c.Publish(activity)
+					}
+				}()
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			match, err := c.Wait(ctx, query)
+
+			if tt.wantId == "" {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("Wait() = %+v, %v, want timeout", match, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Wait() failed: %v", err)
+			}
+			if match.Activity.Id != tt.wantId || match.Activity.Description != tt.wantDescription {
+				t.Errorf("Wait() = %q (%q), want %q (%q)", match.Activity.Id, match.Activity.Description, tt.wantId,
+					tt.wantDescription)
+			}
+		})
+	}
+}
+
+// waitForWaiter blocks until a sync waits on c
+func waitForWaiter(c *Correlator) {
+	for {
+		c.mu.Lock()
+		waiting := len(c.waiters) > 0
+		c.mu.Unlock()
+		if waiting {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,60 @@
+package intervals
+
+import (
+	"fmt"
+	"math"
+	"strava-intervals-description-sync/internal/config"
+	"strings"
+)
+
+// ctlTimeConstant is the number of days of intervals.icu's fitness (CTL) exponentially weighted average
+const ctlTimeConstant = 42
+
+// GenerateFitnessSummary renders configured fields on a single line, e.g. `Load 85 · Fitness 62 (+1.3) · Form -12`.
+// Returns empty string if none of the fields has a value, which is the case until intervals.icu analyzed the activity
+func GenerateFitnessSummary(fields []string, activity *Activity) string {
+	var parts []string
+	for _, field := range fields {
+		switch field {
+		case config.FitnessFieldLoad:
+			if activity.TrainingLoad != nil {
+				parts = append(parts, fmt.Sprintf("Load %.0f", *activity.TrainingLoad))
+			}
+		case config.FitnessFieldIntensity:
+			if activity.Intensity != nil {
+				parts = append(parts, fmt.Sprintf("Intensity %.0f%%", *activity.Intensity))
+			}
+		case config.FitnessFieldFitness:
+			if activity.Ctl == nil {
+				continue
+			}
+			fitness := fmt.Sprintf("Fitness %.0f", *activity.Ctl)
+			if activity.TrainingLoad != nil {
+				fitness += fmt.Sprintf(" (%+.1f)", fitnessChange(*activity.TrainingLoad, *activity.Ctl))
+			}
+			parts = append(parts, fitness)
+		case config.FitnessFieldFatigue:
+			if activity.Atl != nil {
+				parts = append(parts, fmt.Sprintf("Fatigue %.0f", *activity.Atl))
+			}
+		case config.FitnessFieldForm:
+			if activity.Ctl != nil && activity.Atl != nil {
+				parts = append(parts, fmt.Sprintf("Form %.0f", round(*activity.Ctl-*activity.Atl, 0)))
+			}
+		}
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+// fitnessChange is the CTL gained by the activity. CTL after the activity is ctl = prev + (load - prev) / 42, solving
+// for prev gives change = (load - ctl) / 41
+func fitnessChange(load float32, ctl float32) float64 {
+	return round((load-ctl)/(ctlTimeConstant-1), 1)
+}
+
+// round rounds value to given decimals, adding 0 turns `-0` into `0` so small negative values aren't shown as `-0`
+func round(value float32, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(float64(value)*scale)/scale + 0
+}
@@ -0,0 +1,196 @@
+package intervals
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strava-intervals-description-sync/internal/config"
+	"strings"
+	"time"
+)
+
+// ErrNoWorkoutMatch is returned when no candidate event reaches the configured minimum confidence
+var ErrNoWorkoutMatch = errors.New("no workout matched activity with enough confidence")
+
+// scoredCandidate is a single event scored against the activity, used for logging rejected candidates
+type scoredCandidate struct {
+	workout    *Workout
+	confidence float64
+	reason     string
+}
+
+// matchWorkout picks the event intervals.icu paired with the activity, otherwise the highest scoring candidate with
+// confidence of at least cfg.MinConfidence
+func matchWorkout(cfg config.MatchingConfig, activity *Activity, workouts []*Workout) (*WorkoutMatch, error) {
+	for _, workout := range workouts {
+		if workout.Id == activity.PairedEventId && workout.hasSteps() {
+			return &WorkoutMatch{Workout: workout, Strategy: MatchStrategyPairedEvent, Confidence: 1}, nil
+		}
+	}
+
+	var best *scoredCandidate
+	for _, workout := range workouts {
+		candidate := scoreCandidate(cfg, activity, workout)
+		log.Printf("Workout candidate %d %q: confidence %.2f %s", workout.Id, workout.Name, candidate.confidence,
+			candidate.reason)
+		if best == nil || candidate.confidence > best.confidence {
+			best = candidate
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("%w: no events planned", ErrNoWorkoutMatch)
+	}
+	if best.confidence < cfg.MinConfidence {
+		return nil, fmt.Errorf("%w: best candidate %d %q scored %.2f, minimum is %.2f", ErrNoWorkoutMatch,
+			best.workout.Id, best.workout.Name, best.confidence, cfg.MinConfidence)
+	}
+
+	return &WorkoutMatch{Workout: best.workout, Strategy: MatchStrategyScored, Confidence: best.confidence}, nil
+}
+
+// scoreCandidate calculates weighted average of per-criteria scores in [0, 1]. Criteria without data on either side
+// (e.g. no planned distance) are left out instead of counting as mismatch. Date is only a criteria when other days are
+// searched, otherwise it would lift every same day event. Candidates score 0 unless distance or duration is known and at
+// least partially fits, category, sport type and date alone are never enough
+func scoreCandidate(cfg config.MatchingConfig, activity *Activity, workout *Workout) *scoredCandidate {
+	candidate := &scoredCandidate{workout: workout}
+	if !workout.hasSteps() {
+		candidate.reason = "(no workout steps)"
+		return candidate
+	}
+
+	var weighted, totalWeight float64
+	var reasons []string
+	scores := make(map[string]float64)
+	add := func(name string, weight float64, score float64) {
+		weighted += weight * score
+		totalWeight += weight
+		scores[name] = score
+		reasons = append(reasons, fmt.Sprintf("%s=%.2f", name, score))
+	}
+
+	// planned Ride can never be the plan of a Run, regardless of how well distance or duration fit
+	if activity.Type != "" && workout.Type != "" && sportFamily(activity.Type) != sportFamily(workout.Type) {
+		candidate.reason = fmt.Sprintf("(sport type %s doesn't match %s)", workout.Type, activity.Type)
+		return candidate
+	}
+
+	add("category", cfg.CategoryWeight, categoryScore(workout.Category))
+	if activity.Type != "" && workout.Type != "" {
+		add("sport_type", cfg.SportTypeWeight, sportTypeScore(activity.Type, workout.Type))
+	}
+	if cfg.SearchDays > 0 && !activity.StartDateLocal.IsZero() && !workout.StartDateLocal.IsZero() {
+		days := math.Abs(workout.StartDateLocal.date().Sub(activity.StartDateLocal.date()).Hours() / 24)
+		add("date", cfg.DateWeight, linearScore(days, float64(cfg.SearchDays+1)))
+	}
+	if !activity.StartDateLocal.IsZero() && !workout.StartDateLocal.IsZero() && !workout.StartDateLocal.isMidnight() {
+		diff := activity.StartDateLocal.Sub(workout.StartDateLocal.Time)
+		add("start_time", cfg.StartTimeWeight, linearScore(math.Abs(diff.Hours()), cfg.StartTimeTolerance.Hours()))
+	}
+	if activity.Distance > 0 && workout.WorkoutDoc.Distance > 0 {
+		relativeDiff := math.Abs(float64(activity.Distance-workout.WorkoutDoc.Distance)) / float64(workout.WorkoutDoc.Distance)
+		add("distance", cfg.DistanceWeight, linearScore(relativeDiff, cfg.DistanceTolerance))
+	}
+	if activity.MovingTime > 0 && workout.WorkoutDoc.Duration > 0 {
+		relativeDiff := math.Abs(float64(activity.MovingTime-workout.WorkoutDoc.Duration)) / float64(workout.WorkoutDoc.Duration)
+		add("duration", cfg.DurationWeight, linearScore(relativeDiff, cfg.DurationTolerance))
+	}
+
+	candidate.reason = "(" + strings.Join(reasons, " ") + ")"
+	if scores["distance"] == 0 && scores["duration"] == 0 {
+		candidate.reason += " (neither distance nor duration fit)"
+		return candidate
+	}
+
+	if totalWeight > 0 {
+		candidate.confidence = weighted / totalWeight
+	}
+	return candidate
+}
+
+// linearScore is 1 for no difference, falling linearly to 0 at tolerance
+func linearScore(diff float64, tolerance float64) float64 {
+	if tolerance <= 0 {
+		if diff == 0 {
+			return 1
+		}
+		return 0
+	}
+	return math.Max(0, 1-diff/tolerance)
+}
+
+func categoryScore(category string) float64 {
+	switch {
+	case category == "WORKOUT":
+		return 1
+	case strings.HasPrefix(category, "RACE"):
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+// sportTypeScore is 1 for the same sport type, 0.7 for the same family (e.g. Run and TrailRun) and 0 otherwise
+func sportTypeScore(activityType string, workoutType string) float64 {
+	if strings.EqualFold(activityType, workoutType) {
+		return 1
+	}
+	if sportFamily(activityType) == sportFamily(workoutType) {
+		return 0.7
+	}
+	return 0
+}
+
+func sportFamily(sportType string) string {
+	for _, family := range []string{"Run", "Ride", "Swim", "Walk", "Ski"} {
+		if strings.Contains(sportType, family) {
+			return family
+		}
+	}
+	return sportType
+}
+
+func (w *Workout) hasSteps() bool {
+	return w.WorkoutDoc != nil && w.WorkoutDoc.Steps != nil && len(*w.WorkoutDoc.Steps) > 0
+}
+
+// LocalDateTime is intervals.icu's local timestamp format without offset, e.g. `2024-05-01T07:30:00`
+type LocalDateTime struct {
+	time.Time
+}
+
+const localDateTimeLayout = "2006-01-02T15:04:05"
+
+func (t *LocalDateTime) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "" || value == "null" {
+		return nil
+	}
+
+	parsed, err := time.Parse(localDateTimeLayout, value)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+func (t LocalDateTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + t.Format(localDateTimeLayout) + `"`), nil
+}
+
+// date truncates to midnight of the same day
+func (t LocalDateTime) date() time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// isMidnight is true for events planned for a day without specific time
+func (t LocalDateTime) isMidnight() bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0
+}
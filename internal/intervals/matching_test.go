@@ -0,0 +1,98 @@
+package intervals
+
+import (
+	"math"
+	"strava-intervals-description-sync/internal/config"
+	"testing"
+	"time"
+)
+
+func TestScoreCandidate(t *testing.T) {
+	cfg := config.MatchingConfig{
+		SportTypeWeight:    3,
+		CategoryWeight:     2,
+		DateWeight:         2,
+		StartTimeWeight:    1,
+		DistanceWeight:     2,
+		DurationWeight:     2,
+		StartTimeTolerance: 3 * time.Hour,
+		DistanceTolerance:  0.25,
+		DurationTolerance:  0.25,
+	}
+	day := LocalDateTime{time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}
+	steps := &[]WorkoutStep{{Duration: 3600}}
+	workout := func(workoutType string, distance float32, duration float32) *Workout {
+		return &Workout{Id: 1, Type: workoutType, Category: "WORKOUT", StartDateLocal: day,
+			WorkoutDoc: &WorkoutDoc{Steps: steps, Distance: distance, Duration: duration}}
+	}
+	activity := func(distance float32, movingTime float32) *Activity {
+		return &Activity{Type: "Run", StartDateLocal: LocalDateTime{day.Add(7 * time.Hour)}, Distance: distance,
+			MovingTime: movingTime}
+	}
+
+	tests := []struct {
+		name     string
+		activity *Activity
+		workout  *Workout
+		want     float64
+	}{
+		{
+			name:     "distance and duration fit",
+			activity: activity(10000, 3600),
+			workout:  workout("Run", 10000, 3600),
+			want:     1,
+		},
+		{
+			name:     "no planned distance",
+			activity: activity(10000, 3960),
+			workout:  workout("Run", 0, 3600),
+			// category 2×1, sport type 3×1, duration 2×0.6
+			want: 6.2 / 7,
+		},
+		{
+			name:     "activity without moving time",
+			activity: activity(10000, 0),
+			workout:  workout("Run", 10000, 3600),
+			want:     1,
+		},
+		{
+			name:     "only duration doesn't fit",
+			activity: activity(10000, 7200),
+			workout:  workout("Run", 10000, 3600),
+			want:     7.0 / 9,
+		},
+		{
+			name:     "neither distance nor duration planned",
+			activity: activity(10000, 3600),
+			workout:  workout("Run", 0, 0),
+			want:     0,
+		},
+		{
+			name:     "neither distance nor duration fit",
+			activity: activity(20000, 7200),
+			workout:  workout("Run", 10000, 3600),
+			want:     0,
+		},
+		{
+			name:     "other sport",
+			activity: activity(10000, 3600),
+			workout:  workout("Ride", 10000, 3600),
+			want:     0,
+		},
+		{
+			name:     "no workout steps",
+			activity: activity(10000, 3600),
+			workout:  &Workout{Id: 1, Type: "Run", Category: "WORKOUT", WorkoutDoc: &WorkoutDoc{Distance: 10000}},
+			want:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidate := scoreCandidate(cfg, tt.activity, tt.workout)
+			if math.Abs(candidate.confidence-tt.want) > 1e-6 {
+				t.Errorf("scoreCandidate() = %.4f %s, want %.4f", candidate.confidence, candidate.reason, tt.want)
+			}
+		})
+	}
+}
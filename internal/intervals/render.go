@@ -0,0 +1,230 @@
+package intervals
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Renderer renders a workout Summary in an output format
+type Renderer interface {
+	Render(summary *Summary) (string, error)
+}
+
+const (
+	RenderFormatText     = "text"
+	RenderFormatCompact  = "compact"
+	RenderFormatMarkdown = "markdown"
+	RenderFormatHtml     = "html"
+	RenderFormatJson     = "json"
+)
+
+// RenderFormats lists formats accepted by NewRenderer
+var RenderFormats = []string{RenderFormatText, RenderFormatCompact, RenderFormatMarkdown, RenderFormatHtml,
+	RenderFormatJson}
+
+func NewRenderer(format string) (Renderer, error) {
+	switch format {
+	case RenderFormatText:
+		return TextRenderer{}, nil
+	case RenderFormatCompact:
+		return CompactRenderer{}, nil
+	case RenderFormatMarkdown:
+		return MarkdownRenderer{}, nil
+	case RenderFormatHtml:
+		return HtmlRenderer{}, nil
+	case RenderFormatJson:
+		return JsonRenderer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown render format %q, expected one of %s", format, strings.Join(RenderFormats, ", "))
+	}
+}
+
+// targetLabels prefix zones in step lines, heart rate targets have none
+var targetLabels = map[TargetKind]string{TargetPace: "Pace ", TargetPower: "Power "}
+
+// head is the step's duration/distance followed by the stroke for swim steps with a target
+func (s *SummaryStep) head() string {
+	if s.Stroke != "" && s.Target != "" {
+		return s.DurationOrDistance + " " + s.Stroke
+	}
+	return s.DurationOrDistance
+}
+
+// TextRenderer renders a line per step, e.g. `10m @ Z2 (140-150 bpm)`, with repeats as `3X:` followed by `- ` lines.
+// It's the format written to Strava
+type TextRenderer struct{}
+
+func (r TextRenderer) Render(summary *Summary) (string, error) {
+	lines := make([]string, len(summary.Steps))
+	for i, step := range summary.Steps {
+		lines[i] = r.step(step)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r TextRenderer) step(step *SummaryStep) string {
+	if step.isRepeat() {
+		result := fmt.Sprintf("%dX:", step.Repetitions)
+		for _, child := range step.Steps {
+			result += "\n- " + r.step(child)
+		}
+		return result
+	}
+	return stepLine(step, func(zone string) string { return zone }, func(text string) string { return text })
+}
+
+// stepLine formats a single step, zone and other text go through the given functions for markup and escaping. Zone
+// or details are left out when they're unknown
+func stepLine(step *SummaryStep, zone func(string) string, text func(string) string) string {
+	switch {
+	case step.Rest:
+		return text(step.DurationOrDistance + " rest")
+	case step.Target == "":
+		return text(strings.TrimSpace(step.DurationOrDistance + " " + step.Text))
+	}
+
+	line := text(step.head() + " @ " + targetLabels[step.Target])
+	switch {
+	case step.Zone == "":
+		return line + text(step.Details)
+	case step.Details == "":
+		return line + zone(step.Zone)
+	default:
+		return line + zone(step.Zone) + text(" ("+step.Details+")")
+	}
+}
+
+// CompactRenderer renders the workout on a single line, e.g. `15m Z2 · 5×(3m Z4 / 2m Z1) · 10m Z1`. Consecutive
+// identical steps are merged, e.g. three `1km Z3` steps become `3×1km Z3`
+type CompactRenderer struct{}
+
+// compactStep is a step or repeat block in compact notation, merged consecutive identical steps increase count
+type compactStep struct {
+	count int
+	text  string
+	// block is a repeat of more than one step, it's wrapped in parentheses when repeated
+	block bool
+}
+
+func (r CompactRenderer) Render(summary *Summary) (string, error) {
+	return strings.Join(r.steps(summary.Steps), " · "), nil
+}
+
+func (r CompactRenderer) steps(steps []*SummaryStep) []string {
+	var merged []compactStep
+	for _, step := range steps {
+		current := r.step(step)
+		if current.text == "" {
+			continue
+		}
+		if last := len(merged) - 1; last >= 0 && merged[last].text == current.text && merged[last].block == current.block {
+			merged[last].count += current.count
+			continue
+		}
+		merged = append(merged, current)
+	}
+
+	rendered := make([]string, len(merged))
+	for i, step := range merged {
+		switch {
+		case step.count == 1:
+			rendered[i] = step.text
+		case step.block:
+			rendered[i] = fmt.Sprintf("%d×(%s)", step.count, step.text)
+		default:
+			rendered[i] = fmt.Sprintf("%d×%s", step.count, step.text)
+		}
+	}
+	return rendered
+}
+
+// step renders only duration/distance and zone, details are used only if zone is unknown
+func (r CompactRenderer) step(step *SummaryStep) compactStep {
+	if step.isRepeat() {
+		children := r.steps(step.Steps)
+		return compactStep{count: step.Repetitions, text: strings.Join(children, " / "), block: len(children) > 1}
+	}
+
+	var text string
+	switch {
+	case step.Rest:
+		text = step.DurationOrDistance + " rest"
+	case step.Target != "" && step.Zone != "":
+		text = step.head() + " " + step.Zone
+	case step.Target != "":
+		text = step.head() + " " + step.Details
+	case step.DurationOrDistance != "":
+		text = strings.TrimSpace(step.DurationOrDistance + " " + step.Stroke)
+	default:
+		text = step.Text
+	}
+	return compactStep{count: 1, text: strings.TrimSpace(text)}
+}
+
+// MarkdownRenderer renders a nested list with zones in bold
+type MarkdownRenderer struct{}
+
+func (r MarkdownRenderer) Render(summary *Summary) (string, error) {
+	var lines []string
+	r.steps(summary.Steps, "", &lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r MarkdownRenderer) steps(steps []*SummaryStep, indent string, lines *[]string) {
+	for _, step := range steps {
+		if step.isRepeat() {
+			*lines = append(*lines, fmt.Sprintf("%s- **%d×**", indent, step.Repetitions))
+			r.steps(step.Steps, indent+"  ", lines)
+			continue
+		}
+		line := stepLine(step, func(zone string) string { return "**" + escapeMarkdown(zone) + "**" }, escapeMarkdown)
+		*lines = append(*lines, indent+"- "+line)
+	}
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `_`, `\_`, "`", "\\`", `[`, `\[`, `]`, `\]`)
+
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// HtmlRenderer renders a nested `<ul>` list with zones in `<strong>`, e.g. for emails
+type HtmlRenderer struct{}
+
+func (r HtmlRenderer) Render(summary *Summary) (string, error) {
+	var b strings.Builder
+	r.steps(summary.Steps, &b)
+	return b.String(), nil
+}
+
+func (r HtmlRenderer) steps(steps []*SummaryStep, b *strings.Builder) {
+	b.WriteString("<ul>\n")
+	for _, step := range steps {
+		if step.isRepeat() {
+			fmt.Fprintf(b, "<li>%d×\n", step.Repetitions)
+			r.steps(step.Steps, b)
+			b.WriteString("</li>\n")
+			continue
+		}
+		line := stepLine(step, func(zone string) string { return "<strong>" + html.EscapeString(zone) + "</strong>" },
+			html.EscapeString)
+		b.WriteString("<li>" + line + "</li>\n")
+	}
+	b.WriteString("</ul>\n")
+}
+
+// JsonRenderer renders the Summary as indented JSON for other tools
+type JsonRenderer struct{}
+
+func (r JsonRenderer) Render(summary *Summary) (string, error) {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(summary); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
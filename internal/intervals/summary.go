@@ -0,0 +1,43 @@
+package intervals
+
+// TargetKind is what a step's target is based on
+type TargetKind string
+
+const (
+	TargetHeartRate TargetKind = "hr"
+	TargetPace      TargetKind = "pace"
+	TargetPower     TargetKind = "power"
+)
+
+// Summary is the renderer independent summary of a workout, see Renderer
+type Summary struct {
+	Name  string         `json:"name"`
+	Type  string         `json:"type"`
+	Steps []*SummaryStep `json:"steps"`
+}
+
+// SummaryStep is either a repeat block (Repetitions and Steps set) or a single step. Values are already formatted
+// according to athlete's sport settings and DescriptionOptions
+type SummaryStep struct {
+	Repetitions int            `json:"repetitions,omitempty"`
+	Steps       []*SummaryStep `json:"steps,omitempty"`
+
+	// DurationOrDistance e.g. `10m`, `1km` or `100yd`
+	DurationOrDistance string `json:"duration_or_distance,omitempty"`
+	// Stroke is set for swim steps whose text mentions one, e.g. `Freestyle`
+	Stroke string `json:"stroke,omitempty"`
+	// Target is empty for steps without one
+	Target TargetKind `json:"target,omitempty"`
+	// Zone e.g. `Z2` or `Endurance–Tempo`, empty if it couldn't be determined
+	Zone string `json:"zone,omitempty"`
+	// Details are absolute target values, e.g. `140-150 bpm` or `04:30 min/km`
+	Details string `json:"details,omitempty"`
+	// Rest is set for swim rest intervals
+	Rest bool `json:"rest,omitempty"`
+	// Text is the step's text from the workout, renderers show it for steps without a target
+	Text string `json:"text,omitempty"`
+}
+
+func (s *SummaryStep) isRepeat() bool {
+	return s.Repetitions > 0 && len(s.Steps) > 0
+}
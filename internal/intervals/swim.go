@@ -0,0 +1,70 @@
+package intervals
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+const (
+	metersPerYard = 0.9144
+	// paceUnitsSecs100y is sport settings' pace units of athletes that swim in yards
+	paceUnitsSecs100y = "SECS_100Y"
+)
+
+// SportSettingsType returns the sport settings used for a workout of sportType, intervals.icu keeps separate zones for
+// running, riding and swimming
+func SportSettingsType(sportType string) SportType {
+	switch sportFamily(sportType) {
+	case "Swim":
+		return SportTypeSwim
+	case "Ride":
+		return SportTypeRide
+	default:
+		return SportTypeRun
+	}
+}
+
+// isYardPool reports whether pool length (in meters, as intervals.icu stores it) is a whole number of yards but not of
+// meters, e.g. 22.86 for a 25 yd pool
+func isYardPool(poolLength float32) bool {
+	if poolLength <= 0 {
+		return false
+	}
+	yards := float64(poolLength) / metersPerYard
+	return math.Abs(yards-math.Round(yards)) < 0.01 && math.Abs(float64(poolLength)-math.Round(float64(poolLength))) > 0.01
+}
+
+// strokes maps step text keywords to stroke names, first match wins so longer keywords go first
+var strokes = []struct {
+	pattern *regexp.Regexp
+	name    string
+}{
+	{regexp.MustCompile(`(?i)\b(free(style)?|fr)\b`), "Freestyle"},
+	{regexp.MustCompile(`(?i)\b(back(stroke)?|bk)\b`), "Backstroke"},
+	{regexp.MustCompile(`(?i)\b(breast(stroke)?|br)\b`), "Breaststroke"},
+	{regexp.MustCompile(`(?i)\b(fly|butterfly)\b`), "Butterfly"},
+	{regexp.MustCompile(`\bIM\b`), "IM"},
+	{regexp.MustCompile(`(?i)\bdrills?\b`), "Drill"},
+	{regexp.MustCompile(`(?i)\bkick\b`), "Kick"},
+	{regexp.MustCompile(`(?i)\bpull\b`), "Pull"},
+	{regexp.MustCompile(`(?i)\bchoice\b`), "Choice"},
+}
+
+// strokeOf returns the stroke mentioned in step text, or empty string
+func strokeOf(text string) string {
+	for _, stroke := range strokes {
+		if stroke.pattern.MatchString(text) {
+			return stroke.name
+		}
+	}
+	return ""
+}
+
+// isRestStep reports whether a swim step is a rest interval, i.e. it's time based with no target, or says so
+func (w *WorkoutStep) isRestStep() bool {
+	if w.HeartRate != nil || w.Pace != nil || w.Power != nil {
+		return false
+	}
+	return w.Duration > 0 && (w.Distance == 0 || strings.Contains(strings.ToLower(w.Text), "rest"))
+}
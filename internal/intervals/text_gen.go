@@ -3,167 +3,358 @@ package intervals
 import (
 	"fmt"
 	"log"
+	"math"
+	"strava-intervals-description-sync/internal/config"
 	"strings"
 	"time"
 )
 
+// DescriptionOptions control how GenerateDescription renders steps
+type DescriptionOptions struct {
+	// ZoneLabels is `numbers` (Z2), `names` (Endurance, from sport settings) or `both` (Z2 Endurance), see
+	// config.ZoneLabels*
+	ZoneLabels string
+	// PoolLength of the swim activity in meters, pools measured in yards switch swim distances and pace to yards
+	PoolLength float32
+}
+
+// generator holds what's needed to render steps of a single workout
+type generator struct {
+	settings *AthleteSportSettings
+	options  DescriptionOptions
+	// swim renders distances in pool units, pace per 100 and rest intervals
+	swim  bool
+	yards bool
+}
+
+func newGenerator(w *Workout, sportSettings *AthleteSportSettings, options DescriptionOptions) *generator {
+	if sportSettings == nil {
+		sportSettings = &AthleteSportSettings{}
+	}
+	g := &generator{settings: sportSettings, options: options, swim: sportFamily(w.Type) == "Swim"}
+	if g.swim {
+		g.yards = isYardPool(options.PoolLength) || options.PoolLength <= 0 && sportSettings.PaceUnits == paceUnitsSecs100y
+	}
+	return g
+}
+
 // GenerateDescription iterates Workout steps and generates text summary for it
-func (w *Workout) GenerateDescription(sportSettings *AthleteSportSettings) string {
-	var summary string
+func (w *Workout) GenerateDescription(sportSettings *AthleteSportSettings, options DescriptionOptions) string {
+	text, _ := TextRenderer{}.Render(w.Summary(sportSettings, options))
+	return text
+}
 
-	for i, doc := range *w.WorkoutDoc.Steps {
-		if i > 0 {
-			summary += "\n"
-		}
-		summary += doc.generateSummaryLineOrBlock(sportSettings)
+// Summary builds the renderer independent summary of the workout's steps
+func (w *Workout) Summary(sportSettings *AthleteSportSettings, options DescriptionOptions) *Summary {
+	summary := &Summary{Name: w.Name, Type: w.Type}
+	if w.WorkoutDoc == nil || w.WorkoutDoc.Steps == nil {
+		return summary
 	}
+	g := newGenerator(w, sportSettings, options)
 
+	for _, doc := range *w.WorkoutDoc.Steps {
+		summary.Steps = append(summary.Steps, doc.summaryStep(g))
+	}
 	return summary
 }
 
-// generateSummaryLineOrBlock takes a single step and generates summary depending on what type it is:
+// summaryStep takes a single step and summarizes it depending on what type it is:
 // - Repetitions (e.g. repeat step X 3 times)
 //   - Recursively calls this function for each child step (haven't tested or seen multiple level nested repeats)
 //
 // - HeartRate - hr base workout step
 // - Pace - pace based workout step
-func (w *WorkoutStep) generateSummaryLineOrBlock(sportSettings *AthleteSportSettings) string {
-	var result string
-	if w.Repetitions > 0 && len(*w.Steps) > 0 {
-		result += fmt.Sprintf("%dX:\n", w.Repetitions)
-		for i, doc := range *w.Steps {
-			result += fmt.Sprintf("- %s", doc.generateSummaryLineOrBlock(sportSettings))
-			if i != len(*w.Steps)-1 {
-				result += "\n"
-			}
+// - Power - power based workout step
+// - swim steps without a target and distance are rest intervals
+// - anything else is summarized as its duration/distance and text
+func (w *WorkoutStep) summaryStep(g *generator) *SummaryStep {
+	step := &SummaryStep{Text: w.Text}
+	if w.Repetitions > 0 && w.Steps != nil && len(*w.Steps) > 0 {
+		step.Repetitions = w.Repetitions
+		for _, doc := range *w.Steps {
+			step.Steps = append(step.Steps, doc.summaryStep(g))
 		}
-	} else if w.HeartRate != nil {
-		result = w.generateHeartRateDescriptionLine(sportSettings)
-	} else if w.Pace != nil {
-		result = w.generatePaceDescriptionLine(sportSettings)
-	} else {
-		log.Println("Unexpected step type")
+		return step
+	}
+
+	step.DurationOrDistance = w.calculationDurationOrDistanceText(g)
+	if g.swim {
+		step.Stroke = strokeOf(w.Text)
+	}
+	if target, ok := w.target(g); ok {
+		step.Target, step.Zone, step.Details = target.kind, target.zone, target.details
+	} else if g.swim && w.isRestStep() {
+		step.Rest = true
+		step.DurationOrDistance = formatDuration(time.Duration(w.Duration) * time.Second)
+	}
+	return step
+}
+
+// stepTarget is the rendered target of a step, zone or details are empty when unknown
+type stepTarget struct {
+	kind    TargetKind
+	zone    string
+	details string
+}
+
+// target renders the step's hr, pace or power target, ok is false for steps without a target
+func (w *WorkoutStep) target(g *generator) (stepTarget, bool) {
+	switch {
+	case w.HeartRate != nil:
+		return w.heartRateTarget(g), true
+	case w.Pace != nil:
+		return w.paceTarget(g), true
+	case w.Power != nil:
+		return w.powerTarget(g), true
+	default:
+		return stepTarget{}, false
+	}
+}
+
+// bounds returns start and end of the target, both are Value for single value targets
+func (u *WorkoutStepUnit) bounds() (start float32, end float32, isRange bool) {
+	if u.Value == 0 && (u.Start != 0 || u.End != 0) {
+		return u.Start, u.End, true
 	}
-	return result
+	return u.Value, u.Value, false
 }
 
-func (w *WorkoutStep) generateHeartRateDescriptionLine(sportSettings *AthleteSportSettings) string {
-	durationOrDistance := w.calculationDurationOrDistanceText()
+// fallbackText renders a target in units that aren't supported as they are, e.g. `70-80 %mmp`
+func (u *WorkoutStepUnit) fallbackText() string {
+	log.Println("Unsupported workout step units", u.Units)
+	start, end, isRange := u.bounds()
+	if isRange {
+		return strings.TrimSpace(fmt.Sprintf("%g-%g %s", start, end, u.Units))
+	}
+	return strings.TrimSpace(fmt.Sprintf("%g %s", start, u.Units))
+}
+
+// zoneText formats a zone or range of zones labeled as configured, zone 0 means it couldn't be determined
+func (g *generator) zoneText(startZone int, endZone int, names []string) string {
+	if startZone <= 0 || endZone <= 0 {
+		return ""
+	}
+	if g.options.ZoneLabels == config.ZoneLabelsNumbers || g.options.ZoneLabels == "" {
+		if startZone == endZone {
+			return fmt.Sprintf("Z%d", startZone)
+		}
+		return fmt.Sprintf("Z%d-Z%d", startZone, endZone)
+	}
+
+	if startZone == endZone {
+		return g.zoneLabel(startZone, names)
+	}
+	return g.zoneLabel(startZone, names) + "–" + g.zoneLabel(endZone, names)
+}
+
+// zoneLabel is the name and/or number of a single zone, zones without a name fall back to the number
+func (g *generator) zoneLabel(zone int, names []string) string {
+	number := fmt.Sprintf("Z%d", zone)
+	if zone > len(names) || strings.TrimSpace(names[zone-1]) == "" {
+		return number
+	}
 
-	var zoneDetails string
-	var zoneName string
+	name := strings.TrimSpace(names[zone-1])
+	if g.options.ZoneLabels == config.ZoneLabelsBoth {
+		return number + " " + name
+	}
+	return name
+}
+
+// zoneOf returns 1-based index of the first zone whose upper bound is >= value, values above the last bound are in
+// the last zone. 0 if there are no zones
+func zoneOf(value float32, upperBounds []float32) int {
+	for i, upperBound := range upperBounds {
+		if value <= upperBound {
+			return i + 1 // 0 index == Z1
+		}
+	}
+	return len(upperBounds)
+}
+
+func (w *WorkoutStep) heartRateTarget(g *generator) stepTarget {
+	sportSettings := g.settings
+	start, end, isRange := w.HeartRate.bounds()
 
-	switch w.HeartRate.Units {
 	// just the value of hr zone as integer
-	case "hr_zone":
-		zoneName = fmt.Sprintf("Z%d", int(w.HeartRate.Value))
-		// % of max hr
+	if w.HeartRate.Units == "hr_zone" {
+		zone := g.zoneText(int(start), int(end), sportSettings.HeartRateZoneNames)
+		return stepTarget{kind: TargetHeartRate, zone: zone}
+	}
+	toBpm := heartRateConverter(w.HeartRate.Units, sportSettings)
+	if toBpm == nil {
+		return stepTarget{kind: TargetHeartRate, details: w.HeartRate.fallbackText()}
+	}
+
+	hrStart, hrEnd := toBpm(start), toBpm(end)
+	zone := g.zoneText(calculateHeartRateZone(hrStart, sportSettings), calculateHeartRateZone(hrEnd, sportSettings),
+		sportSettings.HeartRateZoneNames)
+	details := fmt.Sprintf("%d bpm", int(hrStart))
+	if isRange {
+		details = fmt.Sprintf("%d-%d bpm", int(hrStart), int(hrEnd))
+	}
+	return stepTarget{kind: TargetHeartRate, zone: zone, details: details}
+}
+
+// heartRateConverter returns function converting values in units to bpm, nil if units aren't supported or sport
+// settings lack the needed value
+func heartRateConverter(units string, sportSettings *AthleteSportSettings) func(value float32) float32 {
+	switch units {
+	// % of max hr
 	case "%hr":
-		if w.HeartRate.Value > 0 {
-			zoneName, zoneDetails = w.calculateSingleHrZoneAndDetails(sportSettings, sportSettings.MaximumHeartRate)
-		} else {
-			zoneName, zoneDetails = w.calculateRangeHrZoneAndDetails(sportSettings, sportSettings.MaximumHeartRate)
+		if sportSettings.MaximumHeartRate > 0 {
+			return func(value float32) float32 { return value / 100 * float32(sportSettings.MaximumHeartRate) }
 		}
 	case "%lthr":
-		if w.HeartRate.Value > 0 {
-			zoneName, zoneDetails = w.calculateSingleHrZoneAndDetails(sportSettings, sportSettings.ThresholdHeartRate)
-		} else {
-			zoneName, zoneDetails = w.calculateRangeHrZoneAndDetails(sportSettings, sportSettings.ThresholdHeartRate)
+		if sportSettings.ThresholdHeartRate > 0 {
+			return func(value float32) float32 { return value / 100 * float32(sportSettings.ThresholdHeartRate) }
 		}
-	default:
-		return ""
+	// % of heart rate reserve, resting hr + % of (max hr - resting hr)
+	case "%hrr":
+		if sportSettings.MaximumHeartRate > 0 && sportSettings.RestingHeartRate > 0 {
+			reserve := float32(sportSettings.MaximumHeartRate - sportSettings.RestingHeartRate)
+			return func(value float32) float32 { return float32(sportSettings.RestingHeartRate) + value/100*reserve }
+		}
+	case "bpm":
+		return func(value float32) float32 { return value }
 	}
-
-	return fmt.Sprintf("%s @ %s (%s)", durationOrDistance, zoneName, zoneDetails)
+	return nil
 }
 
 func calculateHeartRateZone(hrValue float32, sportSettings *AthleteSportSettings) int {
+	upperBounds := make([]float32, len(sportSettings.HeartRateZones))
 	for i, hrZoneUpperValue := range sportSettings.HeartRateZones {
-		if hrValue <= float32(hrZoneUpperValue) {
-			return i + 1 // 0 index == Z1
-		}
+		upperBounds[i] = float32(hrZoneUpperValue)
 	}
-
-	log.Println("Could not find a value for the heart rate zone")
-	return 1
+	return zoneOf(hrValue, upperBounds)
 }
 
-func (w *WorkoutStep) calculateSingleHrZoneAndDetails(sportSettings *AthleteSportSettings, sourceHrValue int) (zone string, details string) {
-	hrValue := w.HeartRate.Value / 100 * float32(sourceHrValue)
-	hrZone := calculateHeartRateZone(hrValue, sportSettings)
-	return fmt.Sprintf("Z%d", hrZone), fmt.Sprintf("%d", int(hrValue))
+// metersPerPaceUnit is the distance of absolute `secs/<distance>` pace units
+var metersPerPaceUnit = map[string]float32{
+	"secs/km":   1000,
+	"secs/mile": 1609.344,
+	"secs/100m": 100,
+	"secs/100y": 91.44,
+	"secs/500m": 500,
 }
 
-func (w *WorkoutStep) calculateRangeHrZoneAndDetails(sportSettings *AthleteSportSettings, sourceHrValue int) (zone string, details string) {
-	hrStart := w.HeartRate.Start / 100 * float32(sourceHrValue)
-	hrEnd := w.HeartRate.End / 100 * float32(sourceHrValue)
+func (w *WorkoutStep) paceTarget(g *generator) stepTarget {
+	sportSettings := g.settings
+	start, end, isRange := w.Pace.bounds()
 
-	hrZoneStart := calculateHeartRateZone(hrStart, sportSettings)
-	hrZoneEnd := calculateHeartRateZone(hrEnd, sportSettings)
-	var zoneName string
-	if hrZoneStart == hrZoneEnd {
-		zoneName = fmt.Sprintf("Z%d", hrZoneStart)
-	} else {
-		zoneName = fmt.Sprintf("Z%d-Z%d", hrZoneStart, hrZoneEnd)
+	// just the value of pace zone as integer
+	if w.Pace.Units == "pace_zone" {
+		return stepTarget{kind: TargetPace, zone: g.zoneText(int(start), int(end), sportSettings.PaceZoneNames)}
+	}
+	toSpeed := speedConverter(w.Pace.Units, sportSettings)
+	if toSpeed == nil || start <= 0 || end <= 0 {
+		return stepTarget{kind: TargetPace, details: w.Pace.fallbackText()}
 	}
-	return zoneName, fmt.Sprintf("%d-%d bpm", int(hrStart), int(hrEnd))
-}
-
-func (w *WorkoutStep) generatePaceDescriptionLine(sportSettings *AthleteSportSettings) string {
-	durationOrDistance := w.calculationDurationOrDistanceText()
 
-	var zoneDetails string
-	var zoneName string
+	speedStart, speedEnd := toSpeed(start), toSpeed(end)
+	var zone string
+	if sportSettings.ThresholdPace > 0 {
+		zone = g.zoneText(calculatePaceZone(speedStart/sportSettings.ThresholdPace*100, sportSettings),
+			calculatePaceZone(speedEnd/sportSettings.ThresholdPace*100, sportSettings), sportSettings.PaceZoneNames)
+	}
+	meters, paceUnit := g.paceDistance()
+	details := fmt.Sprintf("%s %s", formatPace(speedStart, meters), paceUnit)
+	if isRange {
+		details = fmt.Sprintf("%s-%s %s", formatPace(speedStart, meters), formatPace(speedEnd, meters), paceUnit)
+	}
+	return stepTarget{kind: TargetPace, zone: zone, details: details}
+}
 
-	switch w.Pace.Units {
-	// just the value of hr zone as integer
-	case "pace_zone":
-		zoneName = fmt.Sprintf("Z%d", int(w.HeartRate.Value))
-		// % of max hr
+// speedConverter returns function converting values in units to speed in m/s, nil if units aren't supported or sport
+// settings lack the needed value
+func speedConverter(units string, sportSettings *AthleteSportSettings) func(value float32) float32 {
+	switch units {
+	// % of threshold pace
 	case "%pace":
-		if w.Pace.Value > 0 {
-			paceValueMinPerKm := 1 / ((w.Pace.Value / 100 * sportSettings.ThresholdPace) / 1000 * 60)
-			paceDuration := time.Duration(paceValueMinPerKm * float32(time.Minute))
-			paceZone := calculatePaceZone(w.Pace.Value, sportSettings)
-			zoneName = fmt.Sprintf("Z%d", paceZone)
-			zoneDetails = fmt.Sprintf("%s min/km",
-				time.Unix(0, 0).UTC().Add(paceDuration).Format("04:05"))
-		} else {
-			startPaceValueMinPerKm := 1 / ((w.Pace.Start / 100 * sportSettings.ThresholdPace) / 1000 * 60)
-			startPaceValueDuration := time.Duration(startPaceValueMinPerKm * float32(time.Minute))
-			endPaceValueMinPerKm := 1 / ((w.Pace.End / 100 * sportSettings.ThresholdPace) / 1000 * 60)
-			endPaceValueDuration := time.Duration(endPaceValueMinPerKm * float32(time.Minute))
-
-			startPaceZone := calculatePaceZone(w.Pace.Start, sportSettings)
-			endPaceZone := calculatePaceZone(w.Pace.End, sportSettings)
-			if startPaceZone == endPaceZone {
-				zoneName = fmt.Sprintf("Z%d", startPaceZone)
-			} else {
-				zoneName = fmt.Sprintf("Z%d-Z%d", startPaceZone, endPaceZone)
-			}
-			zoneDetails = fmt.Sprintf("%s-%s min/km",
-				time.Unix(0, 0).UTC().Add(startPaceValueDuration).Format("04:05"),
-				time.Unix(0, 0).UTC().Add(endPaceValueDuration).Format("04:05"))
+		if sportSettings.ThresholdPace > 0 {
+			return func(value float32) float32 { return value / 100 * sportSettings.ThresholdPace }
 		}
+	case "m/s":
+		return func(value float32) float32 { return value }
 	default:
-		return ""
+		if meters, ok := metersPerPaceUnit[units]; ok {
+			return func(value float32) float32 { return meters / value }
+		}
 	}
-
-	return fmt.Sprintf("%s @ Pace %s (%s)", durationOrDistance, zoneName, zoneDetails)
+	return nil
 }
 
 func calculatePaceZone(pacePercentage float32, sportSettings *AthleteSportSettings) int {
-	for i, paceZoneUpperPercentage := range sportSettings.PaceZones {
-		if pacePercentage <= paceZoneUpperPercentage {
-			return i + 1 // 0 index == Z1
-		}
+	return zoneOf(pacePercentage, sportSettings.PaceZones)
+}
+
+// paceDistance returns distance in meters pace is shown per and its unit, per km for running and per 100m/100yd for
+// swimming
+func (g *generator) paceDistance() (float32, string) {
+	switch {
+	case g.swim && g.yards:
+		return 100 * metersPerYard, "/100yd"
+	case g.swim:
+		return 100, "/100m"
+	default:
+		return 1000, "min/km"
 	}
+}
 
-	log.Println("Could not find a value for the pace zone")
-	return 1
+// formatPace formats speed (m/s) as time per given distance in meters, e.g. `04:05`
+func formatPace(speed float32, meters float32) string {
+	paceDuration := time.Duration(meters / speed * float32(time.Second)).Round(time.Second)
+	if paceDuration >= time.Hour {
+		return formatDuration(paceDuration)
+	}
+	return time.Unix(0, 0).UTC().Add(paceDuration).Format("04:05")
 }
 
-func (w *WorkoutStep) calculationDurationOrDistanceText() string {
+func (w *WorkoutStep) powerTarget(g *generator) stepTarget {
+	sportSettings := g.settings
+	start, end, isRange := w.Power.bounds()
+
+	// just the value of power zone as integer
+	if w.Power.Units == "power_zone" {
+		return stepTarget{kind: TargetPower, zone: g.zoneText(int(start), int(end), sportSettings.PowerZoneNames)}
+	}
+	toWatts := wattsConverter(w.Power.Units, sportSettings)
+	if toWatts == nil {
+		return stepTarget{kind: TargetPower, details: w.Power.fallbackText()}
+	}
+
+	wattsStart, wattsEnd := toWatts(start), toWatts(end)
+	var zone string
+	if sportSettings.Ftp > 0 {
+		zone = g.zoneText(zoneOf(wattsStart/float32(sportSettings.Ftp)*100, sportSettings.PowerZones),
+			zoneOf(wattsEnd/float32(sportSettings.Ftp)*100, sportSettings.PowerZones), sportSettings.PowerZoneNames)
+	}
+	details := fmt.Sprintf("%d W", int(wattsStart))
+	if isRange {
+		details = fmt.Sprintf("%d-%d W", int(wattsStart), int(wattsEnd))
+	}
+	return stepTarget{kind: TargetPower, zone: zone, details: details}
+}
+
+// wattsConverter returns function converting values in units to watts, nil if units aren't supported or sport
+// settings lack the needed value
+func wattsConverter(units string, sportSettings *AthleteSportSettings) func(value float32) float32 {
+	switch units {
+	case "%ftp":
+		if sportSettings.Ftp > 0 {
+			return func(value float32) float32 { return value / 100 * float32(sportSettings.Ftp) }
+		}
+	case "w":
+		return func(value float32) float32 { return value }
+	}
+	return nil
+}
+
+func (w *WorkoutStep) calculationDurationOrDistanceText(g *generator) string {
+	if g.swim {
+		return w.swimDistanceText(g)
+	}
+
 	distanceText := ""
 	durationText := ""
 
@@ -196,6 +387,20 @@ func (w *WorkoutStep) calculationDurationOrDistanceText() string {
 	}
 }
 
+// swimDistanceText renders swim steps in pool units, never km
+func (w *WorkoutStep) swimDistanceText(g *generator) string {
+	var text string
+	switch {
+	case w.Distance > 0 && g.yards:
+		text = fmt.Sprintf("%dyd", int(math.Round(float64(w.Distance/metersPerYard))))
+	case w.Distance > 0:
+		text = fmt.Sprintf("%dm", int(math.Round(float64(w.Distance))))
+	case w.Duration > 0:
+		text = formatDuration(time.Duration(w.Duration) * time.Second)
+	}
+	return text
+}
+
 func formatDuration(d time.Duration) string {
 	s := d.String()
 	if strings.HasSuffix(s, "m0s") {
@@ -4,7 +4,11 @@ import "time"
 
 type SportType string
 
-const SportTypeRun SportType = "Run"
+const (
+	SportTypeRun  SportType = "Run"
+	SportTypeRide SportType = "Ride"
+	SportTypeSwim SportType = "Swim"
+)
 
 type AthleteSportSettings struct {
 	MaximumHeartRate   int      `json:"max_hr"`
@@ -16,21 +20,110 @@ type AthleteSportSettings struct {
 	// PaceZones in form of percentage of `ThresholdPace`, e.g. `77.5` would be 77.5%
 	PaceZones     []float32 `json:"pace_zones"`
 	PaceZoneNames []string  `json:"pace_zone_names"`
+	// PaceUnits is how the athlete prefers pace, e.g. `MINS_KM` or `SECS_100Y`
+	PaceUnits string `json:"pace_units"`
+	// Ftp in watts
+	Ftp int `json:"ftp"`
+	// PowerZones in form of percentage of `Ftp`
+	PowerZones     []float32 `json:"power_zones"`
+	PowerZoneNames []string  `json:"power_zone_names"`
+	// RestingHeartRate isn't part of sport settings in intervals.icu, GetWorkoutSportSettings fills it from Athlete for
+	// workouts with `%hrr` targets
+	RestingHeartRate int `json:"resting_hr"`
+}
+
+type Athlete struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// Timezone is IANA timezone name, e.g. `Australia/Brisbane`
+	Timezone         string `json:"timezone"`
+	RestingHeartRate int    `json:"icu_resting_hr"`
 }
 
 type Activity struct {
-	StravaId string `json:"strava_id"`
+	Id          string `json:"id"`
+	StravaId    string `json:"strava_id"`
+	Description string `json:"description"`
 	// PairedEventId as far as I'm aware, refers to Workout.Id. It might however to also refer to maybe planned races in calendar?
-	PairedEventId int       `json:"paired_event_id"`
-	StartDate     time.Time `json:"start_date"`
-	Distance      float32   `json:"distance"`
-	MovingTime    float32   `json:"moving_time"`
+	PairedEventId  int           `json:"paired_event_id"`
+	Type           string        `json:"type"`
+	StartDate      time.Time     `json:"start_date"`
+	StartDateLocal LocalDateTime `json:"start_date_local"`
+	Distance       float32       `json:"distance"`
+	MovingTime     float32       `json:"moving_time"`
+	ElapsedTime    float32       `json:"elapsed_time"`
+	// PoolLength of swim activities in meters, 0 otherwise
+	PoolLength float32 `json:"pool_length"`
+	// Feel is athlete's subjective feel, 1 (strong) to 5 (weak), 0 if not set
+	Feel int `json:"feel"`
+	// training values are computed by intervals.icu once the activity is analyzed, nil until then
+	TrainingLoad *float32 `json:"icu_training_load"`
+	// Intensity is in percent of threshold
+	Intensity *float32 `json:"icu_intensity"`
+	// Ctl (fitness) and Atl (fatigue) are athlete's values including this activity
+	Ctl *float32 `json:"icu_ctl"`
+	Atl *float32 `json:"icu_atl"`
 }
 
 type Workout struct {
-	Id         int         `json:"id"`
-	Name       string      `json:"name"`
-	WorkoutDoc *WorkoutDoc `json:"workout_doc"`
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	// Type is the planned sport type, e.g. `Run`, `TrailRun`, `Ride`
+	Type string `json:"type"`
+	// Category is the calendar event category, e.g. `WORKOUT`, `NOTE`, `RACE_A`
+	Category string `json:"category"`
+	// StartDateLocal is midnight unless a specific time was planned
+	StartDateLocal LocalDateTime `json:"start_date_local"`
+	WorkoutDoc     *WorkoutDoc   `json:"workout_doc"`
+}
+
+// WorkoutClass is a coarse classification of a workout derived from its category and structure
+type WorkoutClass string
+
+const (
+	WorkoutClassRace      WorkoutClass = "race"
+	WorkoutClassIntervals WorkoutClass = "intervals"
+	WorkoutClassSteady    WorkoutClass = "steady"
+)
+
+type Webhook struct {
+	Secret string          `json:"secret"`
+	Events []*WebhookEvent `json:"events"`
+}
+
+type WebhookEvent struct {
+	AthleteId string    `json:"athlete_id"`
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	// Activity is only present for activity events
+	Activity *Activity `json:"activity"`
+}
+
+type UpdatableActivity struct {
+	Description string `json:"description"`
+}
+
+type activityMessage struct {
+	Content string `json:"content"`
+}
+
+// MatchStrategy describes how a Workout was matched to an Activity
+type MatchStrategy string
+
+const (
+	// MatchStrategyPairedEvent means intervals.icu itself paired the activity with the workout (Activity.PairedEventId)
+	MatchStrategyPairedEvent MatchStrategy = "paired_event"
+	// MatchStrategyScored means the workout was the best scoring candidate of the day, see config.MatchingConfig
+	MatchStrategyScored MatchStrategy = "scored"
+	// MatchStrategyManual means the workout was picked by the athlete via description tag or admin endpoint
+	MatchStrategyManual MatchStrategy = "manual"
+)
+
+type WorkoutMatch struct {
+	Workout  *Workout
+	Strategy MatchStrategy
+	// Confidence is in [0, 1], always 1 for MatchStrategyPairedEvent
+	Confidence float64
 }
 
 type WorkoutDoc struct {
@@ -52,14 +145,25 @@ type WorkoutStep struct {
 	Text        string           `json:"text"`
 	HeartRate   *WorkoutStepUnit `json:"hr"`
 	Pace        *WorkoutStepUnit `json:"pace"`
+	Power       *WorkoutStepUnit `json:"power"`
 	Steps       *[]WorkoutStep   `json:"steps"`
 	Repetitions int              `json:"reps"`
+	// Ramp steps go linearly from target's Start to End instead of staying within the range
+	Ramp     bool `json:"ramp"`
+	Warmup   bool `json:"warmup"`
+	Cooldown bool `json:"cooldown"`
 }
 
+// WorkoutStepUnit is either a single Value or a Start-End range. Supported Units are
+//   - hr: `%hr` (of max hr), `%lthr`, `%hrr` (of heart rate reserve), `bpm`, `hr_zone`
+//   - pace: `%pace` (of threshold pace), `m/s`, `secs/km`, `secs/mile`, `secs/100m`, `secs/100y`, `secs/500m`,
+//     `pace_zone`
+//   - power: `%ftp`, `w`, `power_zone`
+//
+// other units are rendered as they are
 type WorkoutStepUnit struct {
 	Start float32 `json:"start"`
 	End   float32 `json:"end"`
-	// Units values I have observed so far are `%lthr`, `%pace`
 	Units string  `json:"units"`
 	Value float32 `json:"value"`
 }
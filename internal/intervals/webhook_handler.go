@@ -0,0 +1,67 @@
+package intervals
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+const WebhookUrl string = "/intervals/webhook"
+
+const WebhookEventActivityAnalyzed = "ACTIVITY_ANALYZED"
+
+// HandleWebhook receives intervals.icu webhook events and publishes analyzed activities of the configured athlete to the
+// correlator. Uploaded activities are ignored: they have no paired event, training load or fitness values yet
+func (c *Client) HandleWebhook(w http.ResponseWriter, req *http.Request) {
+	var webhook Webhook
+	if err := json.NewDecoder(req.Body).Decode(&webhook); err != nil {
+		log.Println("Failed to decode intervals webhook", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(webhook.Secret), []byte(c.cfg.WebhookSecret)) != 1 {
+		log.Println("Received intervals webhook with invalid secret")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	for _, event := range webhook.Events {
+		if event.AthleteId != c.cfg.AthleteId || event.Activity == nil {
+			continue
+		}
+		if event.Type != WebhookEventActivityAnalyzed {
+			continue
+		}
+
+		log.Printf("Received intervals webhook %s for activity %s (strava id %q)", event.Type, event.Activity.Id,
+			event.Activity.StravaId)
+		c.correlator.Publish(event.Activity)
+	}
+}
+
+// AwaitActivity returns the intervals.icu activity matching query. When intervals.icu webhooks are configured it waits
+// for the matching webhook first and only falls back to polling with FindActivity if none arrives within the
+// configured timeout
+func (c *Client) AwaitActivity(query *ActivityQuery, from *time.Time, to *time.Time) (*ActivityMatch, error) {
+	if c.correlator != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), c.cfg.WebhookTimeout)
+		defer cancel()
+
+		log.Println("Waiting for intervals.icu webhook for strava activity", query.StravaId)
+		match, err := c.correlator.Wait(ctx, query)
+		if err == nil {
+			return match, nil
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		log.Println("No intervals.icu webhook received in time, falling back to polling")
+	}
+
+	return c.FindActivity(query, from, to)
+}
@@ -0,0 +1,88 @@
+package intervals
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strava-intervals-description-sync/internal/config"
+	"strings"
+	"time"
+)
+
+// Wellness is athlete's wellness record of a single day, values are nil when not recorded
+type Wellness struct {
+	// Id is the date of the record, e.g. `2024-05-31`
+	Id string `json:"id"`
+	// Hrv is rMSSD in ms
+	Hrv        *float32 `json:"hrv"`
+	RestingHR  *int     `json:"restingHR"`
+	SleepSecs  *int     `json:"sleepSecs"`
+	SleepScore *float32 `json:"sleepScore"`
+}
+
+// GetWellness fetches the wellness record of the given day (date part of the local time is used)
+func (c *Client) GetWellness(date time.Time) (*Wellness, error) {
+	client := &http.Client{}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://intervals.icu/api/v1/athlete/%s/wellness/%s",
+		c.cfg.AthleteId, date.Format("2006-01-02")), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth("API_KEY", c.cfg.ApiKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.Println("Received unexpected status code fetching intervals wellness", resp.StatusCode)
+		return nil, fmt.Errorf("unexpected status code fetching intervals wellness: %d", resp.StatusCode)
+	}
+
+	var wellness *Wellness
+	if err = json.NewDecoder(resp.Body).Decode(&wellness); err != nil {
+		return nil, err
+	}
+
+	return wellness, nil
+}
+
+// feelNames are intervals.icu names of Activity.Feel values
+var feelNames = map[int]string{1: "Strong", 2: "Good", 3: "Normal", 4: "Poor", 5: "Weak"}
+
+// GenerateWellnessSummary renders configured fields on a single line, e.g.
+// `HRV 62 ms · Resting HR 48 bpm · Sleep 7h32m · Sleep score 85 · Feel Good`. Feel is taken from the activity as
+// intervals.icu records it per activity. Returns empty string if none of the fields has a value
+func GenerateWellnessSummary(fields []string, wellness *Wellness, activity *Activity) string {
+	var parts []string
+	for _, field := range fields {
+		switch field {
+		case config.WellnessFieldHrv:
+			if wellness != nil && wellness.Hrv != nil {
+				parts = append(parts, fmt.Sprintf("HRV %.0f ms", *wellness.Hrv))
+			}
+		case config.WellnessFieldRestingHr:
+			if wellness != nil && wellness.RestingHR != nil {
+				parts = append(parts, fmt.Sprintf("Resting HR %d bpm", *wellness.RestingHR))
+			}
+		case config.WellnessFieldSleepHours:
+			if wellness != nil && wellness.SleepSecs != nil && *wellness.SleepSecs > 0 {
+				sleep := time.Duration(*wellness.SleepSecs) * time.Second
+				parts = append(parts, fmt.Sprintf("Sleep %dh%02dm", int(sleep.Hours()), int(sleep.Minutes())%60))
+			}
+		case config.WellnessFieldSleepScore:
+			if wellness != nil && wellness.SleepScore != nil {
+				parts = append(parts, fmt.Sprintf("Sleep score %.0f", *wellness.SleepScore))
+			}
+		case config.WellnessFieldFeel:
+			if name, ok := feelNames[activity.Feel]; ok {
+				parts = append(parts, "Feel "+name)
+			}
+		}
+	}
+
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,36 @@
+package intervals
+
+import "strings"
+
+// Class returns WorkoutClassRace for race events, WorkoutClassIntervals for workouts containing repeats and
+// WorkoutClassSteady otherwise
+func (w *Workout) Class() WorkoutClass {
+	if strings.HasPrefix(w.Category, "RACE") {
+		return WorkoutClassRace
+	}
+	if w.WorkoutDoc != nil && w.WorkoutDoc.Steps != nil {
+		for _, step := range *w.WorkoutDoc.Steps {
+			if step.Repetitions > 0 {
+				return WorkoutClassIntervals
+			}
+		}
+	}
+	return WorkoutClassSteady
+}
+
+// usesHeartRateUnits reports whether any step, including steps of repeats, has a heart rate target in units
+func (w *Workout) usesHeartRateUnits(units string) bool {
+	return w.WorkoutDoc != nil && w.WorkoutDoc.Steps != nil && stepsUseHeartRateUnits(*w.WorkoutDoc.Steps, units)
+}
+
+func stepsUseHeartRateUnits(steps []WorkoutStep, units string) bool {
+	for _, step := range steps {
+		if step.HeartRate != nil && step.HeartRate.Units == units {
+			return true
+		}
+		if step.Steps != nil && stepsUseHeartRateUnits(*step.Steps, units) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,447 @@
+package intervals
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WorkoutTextError is a syntax error of a single line of workout text, lines are numbered from 1
+type WorkoutTextError struct {
+	Line   int
+	Reason string
+}
+
+func (e *WorkoutTextError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+var (
+	repeatHeaderRegex = regexp.MustCompile(`(?i)^(?:(.*\S)\s+)?(\d+)x$`)
+	durationRegex     = regexp.MustCompile(`^(\d+h)?(\d+m)?(\d+s)?$`)
+	distanceRegex     = regexp.MustCompile(`(?i)^(\d+(?:\.\d+)?)(km|mtr|mi)$`)
+	zoneRegex         = regexp.MustCompile(`(?i)^z(\d+)(?:-z(\d+))?$`)
+	percentRegex      = regexp.MustCompile(`^(\d+(?:\.\d+)?)(?:-(\d+(?:\.\d+)?))?%$`)
+	wattsRegex        = regexp.MustCompile(`(?i)^(\d+)(?:-(\d+))?w$`)
+	bpmRegex          = regexp.MustCompile(`(?i)^(\d+)(?:-(\d+))?bpm$`)
+	paceRegex         = regexp.MustCompile(`(?i)^(\d+:\d{2})(?:-(\d+:\d{2}))?/(km|mi|100m|100y|500m)$`)
+	speedRegex        = regexp.MustCompile(`(?i)^(\d+(?:\.\d+)?)(?:-(\d+(?:\.\d+)?))?m/s$`)
+)
+
+// distanceUnits are meters per distance unit of workout text
+var distanceUnits = map[string]float32{"km": 1000, "mtr": 1, "mi": 1609.34}
+
+// paceUnits maps workout text pace suffixes to WorkoutStepUnit units
+var paceUnits = map[string]string{
+	"km": "secs/km", "mi": "secs/mile", "100m": "secs/100m", "100y": "secs/100y", "500m": "secs/500m",
+}
+
+// ParseWorkoutText parses intervals.icu workout builder text into the WorkoutDoc the API would return, e.g.
+//
+//	Warmup
+//	- 10m ramp 50-75%
+//
+//	5x
+//	- 3m Z4
+//	- 2m Z1 HR
+//
+//	- 3km 95-100% Pace
+//
+// Steps start with `-`, a `5x` line repeats following steps until an empty line, steps under a `Warmup` or `Cooldown`
+// line are marked as such. Targets without `HR` or `Pace` are power, intervals.icu would pick the sport's default target
+// instead, which isn't known here. Durations and distances aren't derived from each other as that needs sport settings.
+// All invalid lines are reported, as WorkoutTextError joined by errors.Join
+func ParseWorkoutText(text string) (*WorkoutDoc, error) {
+	var steps []WorkoutStep
+	var errs []error
+
+	// repeat is the index of the open repeat in steps, -1 if there is none
+	repeat, repeatLine := -1, 0
+	var warmup, cooldown bool
+	closeRepeat := func() {
+		if repeat >= 0 && len(*steps[repeat].Steps) == 0 {
+			errs = append(errs, &WorkoutTextError{Line: repeatLine, Reason: "repeat has no steps"})
+		}
+		repeat = -1
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+		lineNumber := i + 1
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			closeRepeat()
+			warmup, cooldown = false, false
+		case strings.HasPrefix(line, "-"):
+			step, err := parseWorkoutStep(strings.TrimSpace(line[1:]))
+			if err != nil {
+				errs = append(errs, &WorkoutTextError{Line: lineNumber, Reason: err.Error()})
+				continue
+			}
+			if repeat >= 0 {
+				*steps[repeat].Steps = append(*steps[repeat].Steps, *step)
+				continue
+			}
+			step.Warmup, step.Cooldown = warmup, cooldown
+			steps = append(steps, *step)
+		default:
+			closeRepeat()
+			warmup, cooldown = false, false
+			if match := repeatHeaderRegex.FindStringSubmatch(line); match != nil {
+				reps, _ := strconv.Atoi(match[2])
+				if reps < 1 {
+					errs = append(errs, &WorkoutTextError{Line: lineNumber, Reason: "repeat count must be at least 1"})
+					continue
+				}
+				steps = append(steps, WorkoutStep{Text: match[1], Repetitions: reps, Steps: &[]WorkoutStep{}})
+				repeat, repeatLine = len(steps)-1, lineNumber
+				continue
+			}
+			// other lines are section titles, only warmup and cooldown have a meaning
+			switch strings.ToLower(strings.ReplaceAll(line, " ", "")) {
+			case "warmup":
+				warmup = true
+			case "cooldown":
+				cooldown = true
+			}
+		}
+	}
+	closeRepeat()
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("workout has no steps")
+	}
+	return &WorkoutDoc{Steps: &steps}, nil
+}
+
+// parseWorkoutStep parses a step line without the leading `-`, words that are neither duration, distance, `ramp` nor
+// target make up step's text
+func parseWorkoutStep(line string) (*WorkoutStep, error) {
+	if line == "" {
+		return nil, errors.New("empty step")
+	}
+
+	step := &WorkoutStep{}
+	var text []string
+	var hasLength, hasTarget bool
+	words := strings.Fields(line)
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if !hasLength {
+			if duration, ok := parseStepDuration(word); ok {
+				step.Duration = duration
+				hasLength = true
+				continue
+			}
+			if match := distanceRegex.FindStringSubmatch(word); match != nil {
+				value, _ := strconv.ParseFloat(match[1], 32)
+				step.Distance = float32(value) * distanceUnits[strings.ToLower(match[2])]
+				hasLength = true
+				continue
+			}
+		}
+		if strings.EqualFold(word, "ramp") {
+			step.Ramp = true
+			continue
+		}
+
+		var keyword string
+		if i+1 < len(words) {
+			keyword = strings.ToLower(words[i+1])
+		}
+		kind, unit, consumed, err := parseStepTarget(word, keyword)
+		if err != nil {
+			return nil, err
+		}
+		if unit == nil {
+			text = append(text, word)
+			continue
+		}
+		if hasTarget {
+			return nil, fmt.Errorf("more than one target in %q", line)
+		}
+		hasTarget = true
+		i += consumed
+		switch kind {
+		case TargetHeartRate:
+			step.HeartRate = unit
+		case TargetPace:
+			step.Pace = unit
+		default:
+			step.Power = unit
+		}
+	}
+
+	if !hasLength {
+		return nil, fmt.Errorf("missing duration or distance in %q", line)
+	}
+	if step.Ramp && !hasTarget {
+		return nil, fmt.Errorf("ramp without target in %q", line)
+	}
+	step.Text = strings.Join(text, " ")
+	return step, nil
+}
+
+func parseStepDuration(word string) (float32, bool) {
+	if !durationRegex.MatchString(word) {
+		return 0, false
+	}
+	d, err := time.ParseDuration(word)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return float32(d.Seconds()), true
+}
+
+// targetKeywords are words following a target that pick its kind, e.g. `Z2 HR`
+var targetKeywords = map[string]TargetKind{
+	"hr": TargetHeartRate, "lthr": TargetHeartRate, "hrr": TargetHeartRate, "pace": TargetPace, "power": TargetPower,
+	"ftp": TargetPower,
+}
+
+// percentUnits are units of percentage targets by the keyword following them, `%ftp` without one
+var percentUnits = map[string]string{"hr": "%hr", "lthr": "%lthr", "hrr": "%hrr", "pace": "%pace"}
+
+// parseStepTarget parses word as target, keyword is the following word that may pick the target kind (e.g. `HR`),
+// consumed is 1 when it did. Returns nil unit if word isn't a target
+func parseStepTarget(word string, keyword string) (kind TargetKind, unit *WorkoutStepUnit, consumed int, err error) {
+	keywordKind, hasKeyword := targetKeywords[keyword]
+	consume := func(kind TargetKind) int {
+		if hasKeyword && keywordKind == kind {
+			return 1
+		}
+		return 0
+	}
+	kind = TargetPower
+	if hasKeyword {
+		kind = keywordKind
+	}
+
+	if match := zoneRegex.FindStringSubmatch(word); match != nil {
+		units := map[TargetKind]string{TargetHeartRate: "hr_zone", TargetPace: "pace_zone", TargetPower: "power_zone"}
+		start, _ := strconv.Atoi(match[1])
+		end := start
+		if match[2] != "" {
+			end, _ = strconv.Atoi(match[2])
+		}
+		if start < 1 || end < start {
+			return kind, nil, 0, fmt.Errorf("invalid zone %q", word)
+		}
+		return kind, newWorkoutStepUnit(float32(start), float32(end), units[kind]), consume(kind), nil
+	}
+	if match := percentRegex.FindStringSubmatch(word); match != nil {
+		units, ok := percentUnits[keyword]
+		if !ok {
+			units = "%ftp"
+		}
+		start, end := parseTargetRange(match[1], match[2], parseFloat)
+		return kind, newWorkoutStepUnit(start, end, units), consume(kind), nil
+	}
+	if match := wattsRegex.FindStringSubmatch(word); match != nil {
+		start, end := parseTargetRange(match[1], match[2], parseFloat)
+		return TargetPower, newWorkoutStepUnit(start, end, "w"), consume(TargetPower), nil
+	}
+	if match := bpmRegex.FindStringSubmatch(word); match != nil {
+		start, end := parseTargetRange(match[1], match[2], parseFloat)
+		return TargetHeartRate, newWorkoutStepUnit(start, end, "bpm"), consume(TargetHeartRate), nil
+	}
+	if match := paceRegex.FindStringSubmatch(word); match != nil {
+		start, end := parseTargetRange(match[1], match[2], parsePace)
+		units := paceUnits[strings.ToLower(match[3])]
+		return TargetPace, newWorkoutStepUnit(start, end, units), consume(TargetPace), nil
+	}
+	if match := speedRegex.FindStringSubmatch(word); match != nil {
+		start, end := parseTargetRange(match[1], match[2], parseFloat)
+		return TargetPace, newWorkoutStepUnit(start, end, "m/s"), consume(TargetPace), nil
+	}
+	return kind, nil, 0, nil
+}
+
+func parseTargetRange(start string, end string, parse func(string) float32) (float32, float32) {
+	if end == "" {
+		return parse(start), parse(start)
+	}
+	return parse(start), parse(end)
+}
+
+func parseFloat(value string) float32 {
+	f, _ := strconv.ParseFloat(value, 32)
+	return float32(f)
+}
+
+// parsePace parses `m:ss` to seconds
+func parsePace(value string) float32 {
+	minutes, seconds, _ := strings.Cut(value, ":")
+	return parseFloat(minutes)*60 + parseFloat(seconds)
+}
+
+// newWorkoutStepUnit sets Value for single values and Start-End for ranges, as the API does
+func newWorkoutStepUnit(start float32, end float32, units string) *WorkoutStepUnit {
+	if start == end {
+		return &WorkoutStepUnit{Value: start, Units: units}
+	}
+	return &WorkoutStepUnit{Start: start, End: end, Units: units}
+}
+
+// FormatWorkoutText writes the workout in intervals.icu workout builder syntax, ParseWorkoutText of the result gives
+// back the same WorkoutDoc, except for what the syntax can't express:
+//   - repeats nested in repeats are unrolled
+//   - steps with both distance and duration (intervals.icu derives the duration of distance steps with a pace target)
+//     keep only the distance
+//   - step text containing durations, distances, targets or `ramp` is read back as part of the step
+//   - units other than the ones ParseWorkoutText knows are written as they are and won't parse as a target
+//
+// Power targets are tagged with `Power`, so intervals.icu doesn't read them as the sport's default target
+func FormatWorkoutText(doc *WorkoutDoc) string {
+	if doc == nil || doc.Steps == nil {
+		return ""
+	}
+
+	var blocks []string
+	var block []string
+	// section is the header of the open block of steps outside of repeats
+	section := "-"
+	flush := func() {
+		if len(block) > 0 {
+			blocks = append(blocks, strings.Join(block, "\n"))
+		}
+		block = nil
+	}
+
+	for _, step := range *doc.Steps {
+		if step.Repetitions > 0 && step.Steps != nil {
+			flush()
+			header := fmt.Sprintf("%dx", step.Repetitions)
+			if step.Text != "" {
+				header = step.Text + " " + header
+			}
+			block = append(block, header)
+			block = appendStepLines(block, *step.Steps)
+			flush()
+			section = "-"
+			continue
+		}
+
+		stepSection := ""
+		switch {
+		case step.Warmup:
+			stepSection = "Warmup"
+		case step.Cooldown:
+			stepSection = "Cooldown"
+		}
+		if stepSection != section {
+			flush()
+			if stepSection != "" {
+				block = append(block, stepSection)
+			}
+			section = stepSection
+		}
+		block = append(block, formatStepLine(&step))
+	}
+	flush()
+
+	return strings.Join(blocks, "\n\n")
+}
+
+func appendStepLines(lines []string, steps []WorkoutStep) []string {
+	for _, step := range steps {
+		if step.Repetitions > 0 && step.Steps != nil {
+			for i := 0; i < step.Repetitions; i++ {
+				lines = appendStepLines(lines, *step.Steps)
+			}
+			continue
+		}
+		lines = append(lines, formatStepLine(&step))
+	}
+	return lines
+}
+
+func formatStepLine(step *WorkoutStep) string {
+	parts := []string{"-"}
+	if step.Text != "" {
+		parts = append(parts, step.Text)
+	}
+	switch {
+	case step.Distance > 0:
+		parts = append(parts, formatStepDistance(step.Distance))
+	case step.Duration > 0:
+		parts = append(parts, formatDuration(time.Duration(math.Round(float64(step.Duration)))*time.Second))
+	}
+	if step.Ramp {
+		parts = append(parts, "ramp")
+	}
+
+	switch {
+	case step.HeartRate != nil:
+		parts = append(parts, formatStepTarget(step.HeartRate))
+	case step.Pace != nil:
+		parts = append(parts, formatStepTarget(step.Pace))
+	case step.Power != nil:
+		parts = append(parts, formatStepTarget(step.Power))
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatStepDistance(meters float32) string {
+	if meters >= 1000 {
+		return strconv.FormatFloat(float64(meters/1000), 'f', -1, 32) + "km"
+	}
+	return strconv.FormatFloat(float64(meters), 'f', -1, 32) + "mtr"
+}
+
+func formatStepTarget(unit *WorkoutStepUnit) string {
+	start, end, _ := unit.bounds()
+	formatRange := func(format func(float32) string) string {
+		if start == end {
+			return format(start)
+		}
+		return format(start) + "-" + format(end)
+	}
+	number := func(value float32) string {
+		return strconv.FormatFloat(float64(value), 'f', -1, 32)
+	}
+	zone := func(value float32) string {
+		return "Z" + number(value)
+	}
+
+	switch unit.Units {
+	case "power_zone":
+		return formatRange(zone) + " Power"
+	case "hr_zone":
+		return formatRange(zone) + " HR"
+	case "pace_zone":
+		return formatRange(zone) + " Pace"
+	case "%ftp":
+		return formatRange(number) + "% Power"
+	case "%hr":
+		return formatRange(number) + "% HR"
+	case "%lthr":
+		return formatRange(number) + "% LTHR"
+	case "%hrr":
+		return formatRange(number) + "% HRR"
+	case "%pace":
+		return formatRange(number) + "% Pace"
+	case "w":
+		return formatRange(number) + "w"
+	case "bpm":
+		return formatRange(number) + "bpm"
+	case "m/s":
+		return formatRange(number) + "m/s Pace"
+	}
+	for suffix, units := range paceUnits {
+		if unit.Units == units {
+			return formatRange(func(value float32) string {
+				seconds := int(math.Round(float64(value)))
+				return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+			}) + "/" + suffix + " Pace"
+		}
+	}
+	return unit.fallbackText()
+}
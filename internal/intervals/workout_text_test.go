@@ -0,0 +1,103 @@
+package intervals
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestWorkoutTextRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []WorkoutStep
+		text  string
+	}{
+		{
+			name:  "power zone",
+			steps: []WorkoutStep{{Duration: 600, Power: &WorkoutStepUnit{Value: 2, Units: "power_zone"}}},
+			text:  "- 10m Z2 Power",
+		},
+		{
+			name:  "ftp range",
+			steps: []WorkoutStep{{Duration: 300, Power: &WorkoutStepUnit{Start: 90, End: 95, Units: "%ftp"}}},
+			text:  "- 5m 90-95% Power",
+		},
+		{
+			name:  "watts",
+			steps: []WorkoutStep{{Duration: 90, Power: &WorkoutStepUnit{Value: 250, Units: "w"}}},
+			text:  "- 1m30s 250w",
+		},
+		{
+			name:  "heart rate zone range",
+			steps: []WorkoutStep{{Duration: 3600, HeartRate: &WorkoutStepUnit{Start: 1, End: 2, Units: "hr_zone"}}},
+			text:  "- 1h Z1-Z2 HR",
+		},
+		{
+			name: "heart rate percentages and bpm",
+			steps: []WorkoutStep{
+				{Duration: 60, HeartRate: &WorkoutStepUnit{Value: 80, Units: "%hr"}},
+				{Duration: 60, HeartRate: &WorkoutStepUnit{Value: 95, Units: "%lthr"}},
+				{Duration: 60, HeartRate: &WorkoutStepUnit{Start: 60, End: 70, Units: "%hrr"}},
+				{Duration: 60, HeartRate: &WorkoutStepUnit{Start: 140, End: 150, Units: "bpm"}},
+			},
+			text: "- 1m 80% HR\n- 1m 95% LTHR\n- 1m 60-70% HRR\n- 1m 140-150bpm",
+		},
+		{
+			name: "pace units",
+			steps: []WorkoutStep{
+				{Distance: 3000, Pace: &WorkoutStepUnit{Start: 95, End: 100, Units: "%pace"}},
+				{Distance: 1000, Pace: &WorkoutStepUnit{Value: 270, Units: "secs/km"}},
+				{Distance: 400, Pace: &WorkoutStepUnit{Start: 95, End: 100, Units: "secs/100m"}},
+				{Duration: 600, Pace: &WorkoutStepUnit{Value: 2, Units: "pace_zone"}},
+			},
+			text: "- 3km 95-100% Pace\n- 1km 4:30/km Pace\n- 400mtr 1:35-1:40/100m Pace\n- 10m Z2 Pace",
+		},
+		{
+			name:  "speed",
+			steps: []WorkoutStep{{Duration: 1200, Pace: &WorkoutStepUnit{Start: 3.5, End: 3.8, Units: "m/s"}}},
+			text:  "- 20m 3.5-3.8m/s Pace",
+		},
+		{
+			name: "warmup ramp and cooldown",
+			steps: []WorkoutStep{
+				{Duration: 600, Ramp: true, Warmup: true, Power: &WorkoutStepUnit{Start: 50, End: 75, Units: "%ftp"}},
+				{Duration: 1200, Power: &WorkoutStepUnit{Value: 2, Units: "power_zone"}},
+				{Duration: 300, Cooldown: true, Power: &WorkoutStepUnit{Value: 1, Units: "power_zone"}},
+			},
+			text: "Warmup\n- 10m ramp 50-75% Power\n\n- 20m Z2 Power\n\nCooldown\n- 5m Z1 Power",
+		},
+		{
+			name: "repeat with text",
+			steps: []WorkoutStep{{Text: "Main set", Repetitions: 5, Steps: &[]WorkoutStep{
+				{Text: "hard", Duration: 180, Power: &WorkoutStepUnit{Value: 4, Units: "power_zone"}},
+				{Duration: 120, HeartRate: &WorkoutStepUnit{Value: 1, Units: "hr_zone"}},
+			}}},
+			text: "Main set 5x\n- hard 3m Z4 Power\n- 2m Z1 HR",
+		},
+		{
+			name:  "step without target",
+			steps: []WorkoutStep{{Text: "easy spin", Distance: 500}},
+			text:  "- easy spin 500mtr",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &WorkoutDoc{Steps: &tt.steps}
+			text := FormatWorkoutText(doc)
+			if text != tt.text {
+				t.Fatalf("FormatWorkoutText() = %q, want %q", text, tt.text)
+			}
+
+			parsed, err := ParseWorkoutText(text)
+			if err != nil {
+				t.Fatalf("ParseWorkoutText(%q) failed: %v", text, err)
+			}
+			if !reflect.DeepEqual(parsed, doc) {
+				got, _ := json.Marshal(parsed)
+				want, _ := json.Marshal(doc)
+				t.Errorf("ParseWorkoutText(%q) = %s, want %s", text, got, want)
+			}
+		})
+	}
+}
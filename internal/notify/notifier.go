@@ -0,0 +1,176 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"strava-intervals-description-sync/internal/config"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind is the problem a notification is about
+type Kind string
+
+const (
+	// KindSyncFailed is sent when a sync of an activity fails
+	KindSyncFailed Kind = "sync_failed"
+	// KindStravaToken is sent when the Strava refresh token is rejected and the app has to be re-authorized
+	KindStravaToken Kind = "strava_token"
+	// KindWebhookSubscription is sent when the Strava webhook subscription is gone or points elsewhere
+	KindWebhookSubscription Kind = "webhook_subscription"
+)
+
+// Notification is a single message, Subject identifies the problem for rate limiting (e.g. activity id)
+type Notification struct {
+	Kind        Kind      `json:"kind"`
+	Subject     string    `json:"subject,omitempty"`
+	Title       string    `json:"title"`
+	Reason      string    `json:"reason"`
+	ActivityUrl string    `json:"activity_url,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// text is the plain text body shared by all formats except the generic json one
+func (n *Notification) text() string {
+	text := n.Title + "\n" + n.Reason
+	if n.ActivityUrl != "" {
+		text += "\n" + n.ActivityUrl
+	}
+	return text
+}
+
+// Notifier sends notifications to the configured webhook and/or email. Notifications of the same kind and subject are
+// sent at most once per configured rate limit, so a problem that persists doesn't flood the channel
+type Notifier struct {
+	cfg config.NotificationsConfig
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+func NewNotifier(cfg config.NotificationsConfig) *Notifier {
+	return &Notifier{cfg: cfg, lastSent: make(map[string]time.Time)}
+}
+
+// Enabled reports whether any notification channel is configured
+func (n *Notifier) Enabled() bool {
+	return n.cfg.Webhook.Url != "" || n.cfg.Email.Host != ""
+}
+
+// StravaActivityUrl is the link to the activity in Strava
+func StravaActivityUrl(id int64) string {
+	return "https://www.strava.com/activities/" + strconv.FormatInt(id, 10)
+}
+
+// Notify sends the notification in the background unless an identical one was sent recently, failures are only logged
+func (n *Notifier) Notify(notification *Notification) {
+	if !n.Enabled() || !n.allow(notification) {
+		return
+	}
+	if notification.Time.IsZero() {
+		notification.Time = time.Now()
+	}
+
+	go func() {
+		if n.cfg.Webhook.Url != "" {
+			if err := n.sendWebhook(notification); err != nil {
+				log.Println("Failed to send webhook notification", err)
+			}
+		}
+		if n.cfg.Email.Host != "" {
+			if err := n.sendEmail(notification); err != nil {
+				log.Println("Failed to send email notification", err)
+			}
+		}
+	}()
+}
+
+func (n *Notifier) allow(notification *Notification) bool {
+	key := string(notification.Kind) + "/" + notification.Subject
+	now := time.Now()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if last, ok := n.lastSent[key]; ok && now.Sub(last) < n.cfg.RateLimit {
+		log.Printf("Not sending %s notification, one was sent at %s", key, last.Format(time.RFC3339))
+		return false
+	}
+	n.lastSent[key] = now
+	return true
+}
+
+func (n *Notifier) sendWebhook(notification *Notification) error {
+	var body any
+	header := http.Header{}
+	switch n.cfg.Webhook.Format {
+	case config.NotificationFormatSlack:
+		body = map[string]string{"text": notification.text()}
+	case config.NotificationFormatDiscord:
+		body = map[string]string{"content": notification.text()}
+	case config.NotificationFormatNtfy:
+		// ntfy takes the message as plain text body and metadata as headers
+		header.Set("Title", notification.Title)
+		header.Set("Tags", "warning")
+		if notification.ActivityUrl != "" {
+			header.Set("Click", notification.ActivityUrl)
+		}
+		body = notification.Reason
+	default:
+		body = notification
+	}
+
+	var payload []byte
+	if text, ok := body.(string); ok {
+		payload = []byte(text)
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+		header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.cfg.Webhook.Url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d from notification webhook", resp.StatusCode)
+	}
+	return nil
+}
+
+func (n *Notifier) sendEmail(notification *Notification) error {
+	cfg := n.cfg.Email
+
+	var msg strings.Builder
+	msg.WriteString("From: " + cfg.From + "\r\n")
+	msg.WriteString("To: " + strings.Join(cfg.To, ", ") + "\r\n")
+	msg.WriteString("Subject: " + notification.Title + "\r\n")
+	msg.WriteString("Date: " + notification.Time.Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(notification.text(), "\n", "\r\n") + "\r\n")
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	addr := cfg.Host + ":" + strconv.Itoa(cfg.Port)
+	return smtp.SendMail(addr, auth, cfg.From, cfg.To, []byte(msg.String()))
+}
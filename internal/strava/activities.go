@@ -7,18 +7,18 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"strava-intervals-description-sync/internal/strava/persistence"
 	"strava-intervals-description-sync/internal/util"
+	"time"
 )
 
-func GetActivity(id int64) (*Activity, error) {
+func (c *Client) GetActivity(id int64) (*Activity, error) {
 	getActivityFunc := func() (*http.Response, error) {
 		client := &http.Client{}
 		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://www.strava.com/api/v3/activities/%v", id), nil)
 		if err != nil {
 			return nil, err
 		}
-		token, err := persistence.ReadAccessToken()
+		token, err := c.tokens.ReadAccessToken()
 		if err != nil {
 			return nil, err
 		}
@@ -33,7 +33,7 @@ func GetActivity(id int64) (*Activity, error) {
 	}
 
 	beforeRetryFunc := func(resp *http.Response, err error) error {
-		return RefreshToken()
+		return c.RefreshToken()
 	}
 
 	resp, err := util.SendHttpRequestWithExpRetry(getActivityFunc, shouldRetryFunc, beforeRetryFunc, 1)
@@ -58,7 +58,7 @@ func GetActivity(id int64) (*Activity, error) {
 	return activity, nil
 }
 
-func UpdateActivity(id int64, activity *UpdatableActivity) error {
+func (c *Client) UpdateActivity(id int64, activity *UpdatableActivity) error {
 	updatefunc := func() (*http.Response, error) {
 		client := &http.Client{}
 		jsonBody, err := json.Marshal(activity)
@@ -71,7 +71,7 @@ func UpdateActivity(id int64, activity *UpdatableActivity) error {
 		if err != nil {
 			return nil, err
 		}
-		token, err := persistence.ReadAccessToken()
+		token, err := c.tokens.ReadAccessToken()
 		if err != nil {
 			return nil, err
 		}
@@ -84,7 +84,7 @@ func UpdateActivity(id int64, activity *UpdatableActivity) error {
 	}
 
 	beforeRetryFunc := func(resp *http.Response, err error) error {
-		return RefreshToken()
+		return c.RefreshToken()
 	}
 
 	resp, err := util.SendHttpRequestWithExpRetry(updatefunc, shouldRetryFunc, beforeRetryFunc, 1)
@@ -96,7 +96,65 @@ func UpdateActivity(id int64, activity *UpdatableActivity) error {
 
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		log.Println("Failed to update activity", resp.StatusCode)
+		return fmt.Errorf("unexpected status code updating activity: %d", resp.StatusCode)
 	}
 
 	return nil
 }
+
+// listActivitiesPageSize is the maximum page size Strava allows
+const listActivitiesPageSize = 200
+
+// ListActivities returns all athlete's activities that started within [after, before), newest first
+func (c *Client) ListActivities(after time.Time, before time.Time) ([]*SummaryActivity, error) {
+	var activities []*SummaryActivity
+
+	for page := 1; ; page++ {
+		listFunc := func() (*http.Response, error) {
+			client := &http.Client{}
+			req, err := http.NewRequest(http.MethodGet,
+				fmt.Sprintf("https://www.strava.com/api/v3/athlete/activities?after=%d&before=%d&page=%d&per_page=%d",
+					after.Unix(), before.Unix(), page, listActivitiesPageSize), nil)
+			if err != nil {
+				return nil, err
+			}
+			token, err := c.tokens.ReadAccessToken()
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			return client.Do(req)
+		}
+
+		shouldRetryFunc := func(resp *http.Response, err error) bool {
+			return err == nil && resp.StatusCode == http.StatusUnauthorized
+		}
+
+		beforeRetryFunc := func(resp *http.Response, err error) error {
+			return c.RefreshToken()
+		}
+
+		resp, err := util.SendHttpRequestWithExpRetry(listFunc, shouldRetryFunc, beforeRetryFunc, 1)
+		if err != nil {
+			log.Println("Failed to list activities", err)
+			return nil, err
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			log.Println("Failed to list activities", resp.StatusCode)
+			return nil, fmt.Errorf("unexpected status code listing activities: %d", resp.StatusCode)
+		}
+
+		var pageActivities []*SummaryActivity
+		err = json.NewDecoder(resp.Body).Decode(&pageActivities)
+		_ = resp.Body.Close()
+		if err != nil {
+			log.Println("Failed to decode activities", err)
+			return nil, err
+		}
+
+		activities = append(activities, pageActivities...)
+		if len(pageActivities) < listActivitiesPageSize {
+			return activities, nil
+		}
+	}
+}
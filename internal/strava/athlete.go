@@ -0,0 +1,64 @@
+package strava
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strava-intervals-description-sync/internal/util"
+)
+
+// GetAthlete fetches the authenticated athlete, refreshing the access token once if it has expired
+func (c *Client) GetAthlete() (*Athlete, error) {
+	getAthleteFunc := func() (*http.Response, error) {
+		client := &http.Client{}
+		req, err := http.NewRequest(http.MethodGet, "https://www.strava.com/api/v3/athlete", nil)
+		if err != nil {
+			return nil, err
+		}
+		token, err := c.tokens.ReadAccessToken()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return client.Do(req)
+	}
+
+	shouldRetryFunc := func(resp *http.Response, err error) bool {
+		return err == nil && resp.StatusCode == http.StatusUnauthorized
+	}
+
+	beforeRetryFunc := func(resp *http.Response, err error) error {
+		return c.RefreshToken()
+	}
+
+	resp, err := util.SendHttpRequestWithExpRetry(getAthleteFunc, shouldRetryFunc, beforeRetryFunc, 1)
+	if err != nil {
+		log.Println("Failed to get athlete", err)
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.Println("Unexpected status code fetching athlete", resp.StatusCode)
+		return nil, errors.New("unexpected status code fetching athlete")
+	}
+
+	var athlete *Athlete
+	if err = json.NewDecoder(resp.Body).Decode(&athlete); err != nil {
+		log.Println("Failed to decode athlete", err)
+		return nil, err
+	}
+
+	return athlete, nil
+}
+
+// CheckAccessToken verifies that a stored token exists and Strava accepts it (or it can be refreshed)
+func (c *Client) CheckAccessToken() error {
+	if _, err := c.tokens.ReadAccessToken(); err != nil {
+		return errors.New("no stored access token, authenticate via " + InitiateAuthenticationUrl)
+	}
+
+	_, err := c.GetAthlete()
+	return err
+}
@@ -9,29 +9,28 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/url"
-	"os"
-	"strava-intervals-description-sync/internal/strava/persistence"
+	"strava-intervals-description-sync/internal/notify"
 )
 
-func HandleAuthentication(w http.ResponseWriter, req *http.Request) {
-	redirectUrl, err := getAuthRedirectUrl()
+func (c *Client) HandleAuthentication(w http.ResponseWriter, req *http.Request) {
+	redirectUrl, err := c.getAuthRedirectUrl()
 	if err != nil {
 		log.Println("Failed to generate auth callback url", err)
 	}
 	http.Redirect(w, req,
-		fmt.Sprintf("https://www.strava.com/oauth/authorize?client_id=%s&response_type=code&redirect_uri=%s&approval_prompt=force&scope=read,activity:read_all,activity:write",
-			os.Getenv("STRAVA_CLIENT_ID"),
+		fmt.Sprintf("https://www.strava.com/oauth/authorize?client_id=%s&response_type=code&redirect_uri=%s&approval_prompt=force&scope=read,profile:read_all,activity:read_all,activity:write",
+			c.cfg.ClientId,
 			url.QueryEscape(redirectUrl)), http.StatusFound)
 }
 
-func HandleAuthenticationCallback(w http.ResponseWriter, req *http.Request) {
+func (c *Client) HandleAuthenticationCallback(w http.ResponseWriter, req *http.Request) {
 	code := req.URL.Query().Get("code")
 
 	if code == "" {
 		log.Println("Received strava callback with no code")
 		w.WriteHeader(http.StatusBadRequest)
 	} else {
-		if err := exchangeCodeForToken(code); err != nil {
+		if err := c.exchangeCodeForToken(code); err != nil {
 			log.Println("Failed to exchange code", err)
 		}
 		if _, err := w.Write([]byte("Successfully exchanged code")); err != nil {
@@ -40,8 +39,29 @@ func HandleAuthenticationCallback(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
-func RefreshToken() error {
-	refreshToken, err := persistence.ReadRefreshToken()
+// RefreshToken exchanges the stored refresh token for a new access token. Failure means the app most likely has to be
+// re-authorized, which is notified about
+func (c *Client) RefreshToken() error {
+	err := c.refreshToken()
+	if err != nil {
+		c.notifier.Notify(&notify.Notification{
+			Kind:  notify.KindStravaToken,
+			Title: "Strava token refresh failed",
+			Reason: fmt.Sprintf("Refreshing the Strava access token failed (%v), re-authorize the app at %s", err,
+				c.AuthenticationUrl()),
+		})
+	}
+	return err
+}
+
+// AuthenticationUrl is where the athlete (re-)authorizes the app
+func (c *Client) AuthenticationUrl() string {
+	authUrl, _ := url.JoinPath(c.cfg.CallbackBaseUrl, InitiateAuthenticationUrl)
+	return authUrl
+}
+
+func (c *Client) refreshToken() error {
+	refreshToken, err := c.tokens.ReadRefreshToken()
 	if err != nil {
 		log.Println("Couldn't find local refresh token", err)
 		return err
@@ -58,8 +78,8 @@ func RefreshToken() error {
 		err = writer.WriteField(field, value)
 	}
 
-	write("client_id", os.Getenv("STRAVA_CLIENT_ID"))
-	write("client_secret", os.Getenv("STRAVA_CLIENT_SECRET"))
+	write("client_id", c.cfg.ClientId)
+	write("client_secret", c.cfg.ClientSecret)
 	write("refresh_token", refreshToken)
 	write("grant_type", "refresh_token")
 
@@ -74,10 +94,10 @@ func RefreshToken() error {
 		return err
 	}
 
-	return sendAndHandleTokenRequest(writer, &buf)
+	return c.sendAndHandleTokenRequest(writer, &buf)
 }
 
-func exchangeCodeForToken(code string) error {
+func (c *Client) exchangeCodeForToken(code string) error {
 	var buf bytes.Buffer
 	log.Println("Creating token exchange request")
 
@@ -90,8 +110,8 @@ func exchangeCodeForToken(code string) error {
 		err = writer.WriteField(field, value)
 	}
 
-	write("client_id", os.Getenv("STRAVA_CLIENT_ID"))
-	write("client_secret", os.Getenv("STRAVA_CLIENT_SECRET"))
+	write("client_id", c.cfg.ClientId)
+	write("client_secret", c.cfg.ClientSecret)
 	write("code", code)
 	write("grant_type", "authorization_code")
 
@@ -106,10 +126,10 @@ func exchangeCodeForToken(code string) error {
 		return err
 	}
 
-	return sendAndHandleTokenRequest(writer, &buf)
+	return c.sendAndHandleTokenRequest(writer, &buf)
 }
 
-func sendAndHandleTokenRequest(writer *multipart.Writer, buf *bytes.Buffer) error {
+func (c *Client) sendAndHandleTokenRequest(writer *multipart.Writer, buf *bytes.Buffer) error {
 	resp, err := http.Post("https://www.strava.com/oauth/token", writer.FormDataContentType(), buf)
 	if err != nil {
 		log.Println("Failed to send token exchange request", err)
@@ -127,11 +147,11 @@ func sendAndHandleTokenRequest(writer *multipart.Writer, buf *bytes.Buffer) erro
 		return err
 	}
 
-	if err = persistence.WriteAccessToken(authBody.AccessToken); err != nil {
+	if err = c.tokens.WriteAccessToken(authBody.AccessToken); err != nil {
 		log.Println("Failed to write access token", err)
 		return err
 	}
-	if err = persistence.WriteRefreshToken(authBody.RefreshToken); err != nil {
+	if err = c.tokens.WriteRefreshToken(authBody.RefreshToken); err != nil {
 		log.Println("Failed to write refresh token", err)
 		return err
 	}
@@ -139,6 +159,6 @@ func sendAndHandleTokenRequest(writer *multipart.Writer, buf *bytes.Buffer) erro
 	return nil
 }
 
-func getAuthRedirectUrl() (string, error) {
-	return url.JoinPath(os.Getenv("STRAVA_CALLBACK_BASE_URL"), AuthenticationCallbackUrl)
+func (c *Client) getAuthRedirectUrl() (string, error) {
+	return url.JoinPath(c.cfg.CallbackBaseUrl, AuthenticationCallbackUrl)
 }
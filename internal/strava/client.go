@@ -0,0 +1,18 @@
+package strava
+
+import (
+	"strava-intervals-description-sync/internal/config"
+	"strava-intervals-description-sync/internal/notify"
+	"strava-intervals-description-sync/internal/strava/persistence"
+)
+
+// Client talks to the Strava API on behalf of the configured athlete and serves the auth and webhook endpoints
+type Client struct {
+	cfg      config.StravaConfig
+	tokens   *persistence.TokenStore
+	notifier *notify.Notifier
+}
+
+func NewClient(cfg config.StravaConfig, tokens *persistence.TokenStore, notifier *notify.Notifier) *Client {
+	return &Client{cfg: cfg, tokens: tokens, notifier: notifier}
+}
@@ -6,31 +6,40 @@ import (
 	"path"
 )
 
-// WriteAccessToken writes token to file `access_token` inside configured `TOKEN_STORAGE_DIR`
+// TokenStore keeps Strava tokens as plain files inside a directory
+type TokenStore struct {
+	dir string
+}
+
+func NewTokenStore(dir string) *TokenStore {
+	return &TokenStore{dir: dir}
+}
+
+// WriteAccessToken writes token to file `access_token` inside configured token storage directory
 // it's good enough for my personal use-case, but obviously storing access token in plain text on a file is not ideal
-func WriteAccessToken(value string) error {
-	return writeFile("access_token", value)
+func (s *TokenStore) WriteAccessToken(value string) error {
+	return s.writeFile("access_token", value)
 }
 
-func ReadAccessToken() (string, error) {
-	return readFile("access_token")
+func (s *TokenStore) ReadAccessToken() (string, error) {
+	return s.readFile("access_token")
 }
 
-func WriteRefreshToken(value string) error {
-	return writeFile("refresh_token", value)
+func (s *TokenStore) WriteRefreshToken(value string) error {
+	return s.writeFile("refresh_token", value)
 }
 
-func ReadRefreshToken() (string, error) {
-	return readFile("refresh_token")
+func (s *TokenStore) ReadRefreshToken() (string, error) {
+	return s.readFile("refresh_token")
 }
 
-func writeFile(fileName string, value string) error {
-	if err := os.MkdirAll(os.Getenv("TOKEN_STORAGE_DIR"), os.ModePerm); err != nil {
+func (s *TokenStore) writeFile(fileName string, value string) error {
+	if err := os.MkdirAll(s.dir, os.ModePerm); err != nil {
 		log.Println("Failed to create token storage directory", err)
 		return err
 	}
 
-	f, err := os.Create(path.Join(os.Getenv("TOKEN_STORAGE_DIR"), fileName))
+	f, err := os.Create(path.Join(s.dir, fileName))
 	if err != nil {
 		return err
 	}
@@ -44,13 +53,13 @@ func writeFile(fileName string, value string) error {
 	return nil
 }
 
-func readFile(fileName string) (string, error) {
-	_, err := os.Stat(path.Join(os.Getenv("TOKEN_STORAGE_DIR"), fileName))
+func (s *TokenStore) readFile(fileName string) (string, error) {
+	_, err := os.Stat(path.Join(s.dir, fileName))
 	if err != nil {
 		return "", err
 	}
 
-	data, err := os.ReadFile(path.Join(os.Getenv("TOKEN_STORAGE_DIR"), fileName))
+	data, err := os.ReadFile(path.Join(s.dir, fileName))
 	if err != nil {
 		return "", err
 	}
@@ -1,6 +1,10 @@
 package strava
 
-import "time"
+import (
+	"errors"
+	"strings"
+	"time"
+)
 
 const (
 	InitiateAuthenticationUrl string = "/strava/auth"
@@ -21,25 +25,100 @@ type Webhook struct {
 	OwnerId    int64  `json:"owner_id"`
 }
 
-type subscription struct {
-	Id          int32  `json:"id"`
-	CallbackUrl string `json:"callback_url"`
+// Subscription is the Strava webhook push subscription, there can be only one per Strava app
+type Subscription struct {
+	Id          int32     `json:"id"`
+	CallbackUrl string    `json:"callback_url"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 type Activity struct {
-	Description    string    `json:"description"`
-	Name           string    `json:"name"`
-	Commute        bool      `json:"commute"`
-	Trainer        bool      `json:"trainer"`
-	HideFromHome   bool      `json:"hide_from_home"`
-	SportType      string    `json:"sport_type"`
-	GearId         string    `json:"gear_id"`
-	StartDate      time.Time `json:"start_date"`
+	Id           int64     `json:"id"`
+	Description  string    `json:"description"`
+	Name         string    `json:"name"`
+	Commute      bool      `json:"commute"`
+	Trainer      bool      `json:"trainer"`
+	HideFromHome bool      `json:"hide_from_home"`
+	SportType    string    `json:"sport_type"`
+	GearId       string    `json:"gear_id"`
+	StartDate    time.Time `json:"start_date"`
+	// StartDateLocal is local wall-clock time, but Strava marks it as UTC
 	StartDateLocal time.Time `json:"start_date_local"`
+	Distance       float32   `json:"distance"`
+	ElapsedTime    int       `json:"elapsed_time"`
+	// Timezone is in form of `(GMT+10:00) Australia/Brisbane`
+	Timezone string `json:"timezone"`
+}
+
+// Location parses the IANA timezone name out of Timezone
+func (a *Activity) Location() (*time.Location, error) {
+	_, name, found := strings.Cut(a.Timezone, ") ")
+	if !found {
+		name = a.Timezone
+	}
+	if name == "" {
+		return nil, errors.New("activity has no timezone")
+	}
+	return time.LoadLocation(name)
+}
+
+// SummaryActivity is the reduced representation returned when listing activities, it doesn't contain description
+type SummaryActivity struct {
+	Id        int64     `json:"id"`
+	Name      string    `json:"name"`
+	SportType string    `json:"sport_type"`
+	StartDate time.Time `json:"start_date"`
 }
 
 type UpdatableActivity struct {
 	Description string `json:"description"`
+	// Name is only updated when not empty
+	Name string `json:"name,omitempty"`
+	// SportType and flags below are only updated when set
+	SportType    string `json:"sport_type,omitempty"`
+	Commute      *bool  `json:"commute,omitempty"`
+	Trainer      *bool  `json:"trainer,omitempty"`
+	HideFromHome *bool  `json:"hide_from_home,omitempty"`
+	GearId       string `json:"gear_id,omitempty"`
+}
+
+type Athlete struct {
+	Id        int64  `json:"id"`
+	FirstName string `json:"firstname"`
+	LastName  string `json:"lastname"`
+	// Shoes and Bikes are only returned with `profile:read_all` scope
+	Shoes []Gear `json:"shoes"`
+	Bikes []Gear `json:"bikes"`
+}
+
+type Gear struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// Primary is the athlete's default gear, Strava assigns it to new activities of matching type
+	Primary  bool    `json:"primary"`
+	Retired  bool    `json:"retired"`
+	Distance float64 `json:"distance"`
+}
+
+// HasGear reports whether athlete's shoes or bikes were returned, Strava only returns them with `profile:read_all` scope
+func (a *Athlete) HasGear() bool {
+	return len(a.Shoes) > 0 || len(a.Bikes) > 0
+}
+
+// IsDefaultGear reports whether gearId is athlete's primary shoes or bike
+func (a *Athlete) IsDefaultGear(gearId string) bool {
+	for _, gear := range a.Shoes {
+		if gear.Primary && gear.Id == gearId {
+			return true
+		}
+	}
+	for _, gear := range a.Bikes {
+		if gear.Primary && gear.Id == gearId {
+			return true
+		}
+	}
+	return false
 }
 
 type tokenResponse struct {
@@ -4,18 +4,17 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"os"
 	"strconv"
 )
 
-func HandleWebhookRegistrationRequest(w http.ResponseWriter, req *http.Request) {
+func (c *Client) HandleWebhookRegistrationRequest(w http.ResponseWriter, req *http.Request) {
 	log.Println("Received webhook registration request")
 
 	mode := req.URL.Query().Get("hub.mode")
 	token := req.URL.Query().Get("hub.verify_token")
 	challenge := req.URL.Query().Get("hub.challenge")
 
-	if mode == "subscribe" && token == os.Getenv("STRAVA_VERIFY_TOKEN") {
+	if mode == "subscribe" && token == c.cfg.VerifyToken {
 		if _, err := w.Write([]byte("{\"hub.challenge\":\"" + challenge + "\"}")); err != nil {
 			log.Printf("Failed to write response: %s", err)
 		}
@@ -27,19 +26,20 @@ func HandleWebhookRegistrationRequest(w http.ResponseWriter, req *http.Request)
 	}
 }
 
-func ShouldProcessWebhook(w http.ResponseWriter, req *http.Request) (shouldProcess bool, activityId int64) {
-	var webhook Webhook
+// ShouldProcessWebhook decodes the webhook and reports whether it's a create or update of the configured athlete's
+// activity
+func (c *Client) ShouldProcessWebhook(w http.ResponseWriter, req *http.Request) (shouldProcess bool, webhook *Webhook) {
 	if err := json.NewDecoder(req.Body).Decode(&webhook); err != nil {
 		log.Println("Failed to decode webhook", err)
 		w.WriteHeader(http.StatusBadRequest)
-		return false, 0
+		return false, nil
 	}
 
-	if strconv.Itoa(int(webhook.OwnerId)) == os.Getenv("STRAVA_CLIENT_ATHLETE_ID") &&
-		(webhook.AspectType == WebhookAspectTypeCreate) &&
+	if strconv.Itoa(int(webhook.OwnerId)) == c.cfg.AthleteId &&
+		(webhook.AspectType == WebhookAspectTypeCreate || webhook.AspectType == WebhookAspectTypeUpdate) &&
 		webhook.ObjectType == WebhookObjectTypeActivity {
-		return true, webhook.ObjectId
+		return true, webhook
 	}
 
-	return false, 0
+	return false, nil
 }
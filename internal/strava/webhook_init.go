@@ -10,24 +10,41 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/url"
-	"os"
+	"strava-intervals-description-sync/internal/config"
 )
 
-func InitiateWebhookRegistration() error {
-	sub, err := getSubscription()
+// InitiateWebhookRegistration makes sure the webhook subscription points to this instance, creating it or replacing
+// one with a different callback url. In `verify` webhook mode it only logs whether it does, as the subscription may
+// belong to another instance sharing the Strava app
+func (c *Client) InitiateWebhookRegistration() error {
+	if c.cfg.WebhookMode == config.WebhookModeVerify {
+		err := c.CheckWebhookSubscription()
+		if errors.Is(err, ErrNoWebhookSubscription) {
+			log.Println("Strava webhook subscription doesn't point to this instance, leaving it as it is:", err)
+			return nil
+		}
+		if err != nil {
+			log.Println("Failed to verify Strava webhook subscription")
+			return err
+		}
+		log.Println("Found correct existing Strava webhook subscription")
+		return nil
+	}
+
+	sub, err := c.GetSubscription()
 	if err != nil {
 		log.Println("Failed to fetch Strava webhook subscription")
 		return err
 	}
 
 	if sub != nil {
-		desiredCallbackUrl, _ := getWebhookCallbackUrl()
+		desiredCallbackUrl, _ := c.WebhookCallbackUrl()
 		if sub.CallbackUrl == desiredCallbackUrl {
 			log.Println("Found correct existing Strava webhook subscription")
 			return nil
 		} else {
 			log.Println("Found existing Strava webhook subscription with incorrect webhook url, recreating..")
-			err = deleteSubscription(sub)
+			err = c.DeleteSubscription(sub.Id)
 			if err != nil {
 				log.Println("Failed to delete Strava webhook subscription")
 				return err
@@ -35,7 +52,7 @@ func InitiateWebhookRegistration() error {
 		}
 	}
 
-	if err = createSubscription(); err != nil {
+	if _, err = c.CreateSubscription(); err != nil {
 		log.Println("Failed to create Strava webhook subscription")
 		return err
 	}
@@ -43,11 +60,39 @@ func InitiateWebhookRegistration() error {
 	return nil
 }
 
-func createSubscription() error {
+// ErrNoWebhookSubscription means the push subscription doesn't exist or points to another callback url, so no webhooks
+// are received
+var ErrNoWebhookSubscription = errors.New("no webhook subscription registered")
+
+// CheckWebhookSubscription verifies that a push subscription exists and points to the expected callback url
+func (c *Client) CheckWebhookSubscription() error {
+	sub, err := c.GetSubscription()
+	if err != nil {
+		return err
+	}
+	if sub == nil {
+		return ErrNoWebhookSubscription
+	}
+
+	desiredCallbackUrl, err := c.WebhookCallbackUrl()
+	if err != nil {
+		return err
+	}
+	if sub.CallbackUrl != desiredCallbackUrl {
+		return fmt.Errorf("%w, callback url %s doesn't match %s", ErrNoWebhookSubscription, sub.CallbackUrl,
+			desiredCallbackUrl)
+	}
+
+	return nil
+}
+
+// CreateSubscription creates the webhook subscription for this instance, Strava validates it by calling the webhook
+// callback url so the server has to be reachable. Returns id of the new subscription
+func (c *Client) CreateSubscription() (int32, error) {
 	var buf bytes.Buffer
 	log.Println("Creating webhook registration request")
 
-	callbackUrl, err := getWebhookCallbackUrl()
+	callbackUrl, err := c.WebhookCallbackUrl()
 	writer := multipart.NewWriter(&buf)
 	write := func(field, value string) {
 		if err != nil {
@@ -56,84 +101,115 @@ func createSubscription() error {
 		err = writer.WriteField(field, value)
 	}
 
-	write("client_id", os.Getenv("STRAVA_CLIENT_ID"))
-	write("client_secret", os.Getenv("STRAVA_CLIENT_SECRET"))
-	write("verify_token", os.Getenv("STRAVA_VERIFY_TOKEN"))
+	write("client_id", c.cfg.ClientId)
+	write("client_secret", c.cfg.ClientSecret)
+	write("verify_token", c.cfg.VerifyToken)
 	write("callback_url", callbackUrl)
 
 	if err != nil {
 		log.Printf("Failed to create webhook registration request: %s", err)
-		return err
+		return 0, err
 	}
 
 	err = writer.Close()
 	if err != nil {
 		log.Printf("Failed to close form writer: %s", err)
-		return err
+		return 0, err
 	}
 
 	resp, err := http.Post("https://www.strava.com/api/v3/push_subscriptions", writer.FormDataContentType(), &buf)
 	if err != nil {
 		log.Printf("Failed to send webhook registration request: %s", err)
-		return err
+		return 0, err
 	}
+	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
-		log.Printf("Webhook registration request successful")
-		return nil
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.Println("Unexpected webhook registration status code", resp.StatusCode)
+		b, err := io.ReadAll(resp.Body)
+		if err == nil {
+			log.Println(string(b))
+		}
+		return 0, errors.New("strava webhook registration failed")
 	}
 
-	log.Println("Unexpected webhook registration status code", resp.StatusCode)
-	b, err := io.ReadAll(resp.Body)
-	if err == nil {
-		log.Println(string(b))
+	var created Subscription
+	if err = json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		log.Printf("Failed to unmarshal response body: %s", err)
+		return 0, err
 	}
-	return errors.New("strava webhook registration failed")
+	log.Printf("Webhook registration request successful")
+	return created.Id, nil
 }
 
-func getSubscription() (*subscription, error) {
+// ListSubscriptions fetches webhook subscriptions of the Strava app, Strava allows at most one
+func (c *Client) ListSubscriptions() ([]Subscription, error) {
 	resp, err := http.Get(fmt.Sprintf("https://www.strava.com/api/v3/push_subscriptions?client_id=%s&client_secret=%s",
-		os.Getenv("STRAVA_CLIENT_ID"), os.Getenv("STRAVA_CLIENT_SECRET")))
-
+		c.cfg.ClientId, c.cfg.ClientSecret))
 	if err != nil {
 		return nil, err
 	}
+	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		var subs []subscription
-		if err := json.Unmarshal(bodyBytes, &subs); err != nil {
-			log.Printf("Failed to unmarshal response body: %s", err)
-			return nil, err
-		}
-
-		if len(subs) > 0 {
-			return &subs[0], nil
-		}
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.Println("Unexpected status code fetching Strava webhook subscriptions", resp.StatusCode)
+		log.Println(string(bodyBytes))
+		return nil, fmt.Errorf("unexpected status code fetching strava webhook subscriptions: %d", resp.StatusCode)
+	}
 
-		return nil, nil
+	var subs []Subscription
+	if err := json.Unmarshal(bodyBytes, &subs); err != nil {
+		log.Printf("Failed to unmarshal response body: %s", err)
+		return nil, err
 	}
+	return subs, nil
+}
 
+// GetSubscription returns the webhook subscription of the Strava app, nil if there is none
+func (c *Client) GetSubscription() (*Subscription, error) {
+	subs, err := c.ListSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	if len(subs) > 0 {
+		return &subs[0], nil
+	}
 	return nil, nil
 }
 
-func deleteSubscription(sub *subscription) error {
+// DeleteSubscription deletes the webhook subscription, no webhooks are received by any instance sharing the Strava app
+// until a new one is created
+func (c *Client) DeleteSubscription(id int32) error {
 	client := &http.Client{}
 	req, err := http.NewRequest(http.MethodDelete,
-		fmt.Sprintf("https://www.strava.com/api/v3/push_subscriptions/%v?client_id=%s&client_secret=%s",
-			sub.Id, os.Getenv("STRAVA_CLIENT_ID"), os.Getenv("STRAVA_CLIENT_SECRET")), nil)
+		fmt.Sprintf("https://www.strava.com/api/v3/push_subscriptions/%d?client_id=%s&client_secret=%s",
+			id, c.cfg.ClientId, c.cfg.ClientSecret), nil)
 	if err != nil {
 		log.Printf("Failed to create delete subscription request: %s", err)
 		return err
 	}
-	_, err = client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		log.Printf("Failed to delete subscription request: %s", err)
+		return err
 	}
+	defer func() { _ = resp.Body.Close() }()
 
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.Println("Unexpected status code deleting Strava webhook subscription", resp.StatusCode)
+		if b, err := io.ReadAll(resp.Body); err == nil {
+			log.Println(string(b))
+		}
+		return fmt.Errorf("unexpected status code deleting strava webhook subscription %d: %d", id, resp.StatusCode)
+	}
 	return nil
 }
 
-func getWebhookCallbackUrl() (string, error) {
-	return url.JoinPath(os.Getenv("STRAVA_CALLBACK_BASE_URL"), WebhookUrl)
+// WebhookCallbackUrl is the callback url subscriptions of this instance point to
+func (c *Client) WebhookCallbackUrl() (string, error) {
+	return url.JoinPath(c.cfg.CallbackBaseUrl, WebhookUrl)
 }
@@ -0,0 +1,125 @@
+package syncer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strava-intervals-description-sync/internal/history"
+	"time"
+)
+
+const (
+	UnsyncUrl string = "/admin/unsync"
+	PairUrl   string = "/admin/pair"
+)
+
+type unsyncRequest struct {
+	// StravaActivityId unsyncs a single activity, otherwise From and To (YYYY-MM-DD or RFC3339) are required
+	StravaActivityId int64  `json:"activity_id"`
+	From             string `json:"from"`
+	To               string `json:"to"`
+	DryRun           bool   `json:"dry_run"`
+}
+
+// HandleUnsync removes summaries from a single activity or all activities in a date range, responds with
+// an UnsyncResult per activity
+func (s *Syncer) HandleUnsync(w http.ResponseWriter, req *http.Request) {
+	var body unsyncRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var results []*UnsyncResult
+	if body.StravaActivityId != 0 {
+		result, err := s.Unsync(body.StravaActivityId, body.DryRun)
+		if err != nil {
+			log.Println("Failed to unsync activity", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		results = append(results, result)
+	} else {
+		from, to, err := ParseRange(body.From, body.To)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if results, err = s.UnsyncRange(from, to, body.DryRun); err != nil {
+			log.Println("Failed to unsync activities", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		log.Println("Failed to write response", err)
+	}
+}
+
+type pairRequest struct {
+	StravaActivityId int64 `json:"activity_id"`
+	// EventId is the intervals.icu event to pair the activity with, with NoSync unset and EventId 0 the override
+	// is removed and automatic matching is used again
+	EventId int  `json:"event_id"`
+	NoSync  bool `json:"nosync"`
+}
+
+// HandlePair stores a manual workout override for the activity and re-renders its summary, responds with the
+// resulting history record (null if nothing had to change)
+func (s *Syncer) HandlePair(w http.ResponseWriter, req *http.Request) {
+	var body pairRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.StravaActivityId == 0 {
+		http.Error(w, "invalid request body, activity_id is required", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if body.EventId == 0 && !body.NoSync {
+		err = s.history.DeleteOverride(body.StravaActivityId)
+	} else {
+		err = s.history.SetOverride(&history.Override{
+			StravaActivityId: body.StravaActivityId,
+			EventId:          body.EventId,
+			NoSync:           body.NoSync,
+			CreatedAt:        time.Now(),
+		})
+	}
+	if err != nil {
+		log.Println("Failed to store override", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	record := s.Resync(body.StravaActivityId)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(record); err != nil {
+		log.Println("Failed to write response", err)
+	}
+}
+
+// ParseRange parses an activity date range, a date-only `to` is inclusive of that whole day
+func ParseRange(fromValue string, toValue string) (time.Time, time.Time, error) {
+	if fromValue == "" || toValue == "" {
+		return time.Time{}, time.Time{}, errors.New("either activity id or both from and to are required")
+	}
+	from, err := history.ParseTime(fromValue)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from: %w", err)
+	}
+	to, err := history.ParseTime(toValue)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid to: %w", err)
+	}
+	if len(toValue) == len(time.DateOnly) {
+		to = to.AddDate(0, 0, 1)
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, errors.New("from must be before to")
+	}
+	return from, to, nil
+}
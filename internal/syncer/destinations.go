@@ -0,0 +1,106 @@
+package syncer
+
+import (
+	"bytes"
+	"log"
+	"strava-intervals-description-sync/internal/config"
+	"strava-intervals-description-sync/internal/history"
+	"strava-intervals-description-sync/internal/intervals"
+	"strings"
+	"text/template"
+)
+
+// destination renders the summary for a single destination, it's nil when the destination is disabled
+type destination struct {
+	mode     string
+	template *template.Template
+}
+
+type destinationTemplateData struct {
+	Summary string
+	Workout *intervals.Workout
+}
+
+func newDestination(cfg config.DestinationConfig) (*destination, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("destination").Parse(cfg.Template)
+	if err != nil {
+		return nil, err
+	}
+	return &destination{mode: cfg.Mode, template: tmpl}, nil
+}
+
+func (d *destination) render(summary string, workout *intervals.Workout) (string, error) {
+	var buf bytes.Buffer
+	if err := d.template.Execute(&buf, destinationTemplateData{Summary: summary, Workout: workout}); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// RenderTemplate renders summary with a destination template outside of syncs, e.g. to preview a template
+func RenderTemplate(text string, summary string, workout *intervals.Workout) (string, error) {
+	d, err := newDestination(config.DestinationConfig{Enabled: true, Template: text})
+	if err != nil {
+		return "", err
+	}
+	return d.render(summary, workout)
+}
+
+// appendSummary appends SummarySeparator and summary to the end of description
+func appendSummary(description string, summary string) string {
+	if description == "" {
+		return SummarySeparator + "\n" + summary
+	}
+	return description + "\n" + SummarySeparator + "\n" + summary
+}
+
+// writeToIntervals writes the rendered summary to the intervals.icu activity as configured. Messages can't be edited,
+// so the summary is posted only once per activity and re-syncs keep the first one
+func (s *Syncer) writeToIntervals(record *history.Record, activity *intervals.Activity, summary string,
+	workout *intervals.Workout) error {
+	text, err := s.intervalsDestination.render(summary, workout)
+	if err != nil {
+		return err
+	}
+
+	if s.intervalsDestination.mode == config.DestinationModeMessage {
+		posted, err := s.intervalsMessagePosted(record.StravaActivityId, activity.Id)
+		if err != nil {
+			return err
+		}
+		if posted {
+			log.Println("Summary was already posted as intervals.icu message, not posting it again")
+			return nil
+		}
+		if err = s.intervals.AddActivityMessage(activity.Id, text); err != nil {
+			return err
+		}
+		record.IntervalsMessagePosted = true
+		return nil
+	}
+
+	description := appendSummary(StripSummary(activity.Description), text)
+	if description == activity.Description {
+		return nil
+	}
+	return s.intervals.UpdateActivity(activity.Id, &intervals.UpdatableActivity{Description: description})
+}
+
+// intervalsMessagePosted reports whether an earlier sync of the Strava activity posted the summary as message to the
+// intervals.icu activity
+func (s *Syncer) intervalsMessagePosted(stravaActivityId int64, intervalsActivityId string) (bool, error) {
+	syncs, err := s.history.List(history.Query{StravaActivityId: stravaActivityId, Status: history.StatusSynced})
+	if err != nil {
+		return false, err
+	}
+	for _, sync := range syncs {
+		if sync.IntervalsMessagePosted && sync.IntervalsActivityId == intervalsActivityId {
+			return true, nil
+		}
+	}
+	return false, nil
+}
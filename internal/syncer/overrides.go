@@ -0,0 +1,42 @@
+package syncer
+
+import (
+	"log"
+	"regexp"
+	"strava-intervals-description-sync/internal/strava"
+	"strconv"
+)
+
+var (
+	workoutTagRegex = regexp.MustCompile(`(?i)#workout:(\d+)`)
+	noSyncTagRegex  = regexp.MustCompile(`(?i)#nosync\b`)
+)
+
+// activityOverride is the athlete's manual choice for an activity, zero value means automatic matching
+type activityOverride struct {
+	eventId int
+	noSync  bool
+}
+
+// override reads `#nosync` and `#workout:<eventId>` tags from activity title and description (ignoring the summary),
+// falling back to an override stored via admin endpoint. Tags take precedence as they're the most recent intent
+func (s *Syncer) override(activity *strava.Activity) (activityOverride, error) {
+	text := activity.Name + "\n" + StripSummary(activity.Description)
+
+	if noSyncTagRegex.MatchString(text) {
+		return activityOverride{noSync: true}, nil
+	}
+	if match := workoutTagRegex.FindStringSubmatch(text); match != nil {
+		eventId, err := strconv.Atoi(match[1])
+		if err == nil {
+			return activityOverride{eventId: eventId}, nil
+		}
+		log.Printf("Ignoring invalid workout tag %q", match[0])
+	}
+
+	stored, err := s.history.GetOverride(activity.Id)
+	if err != nil || stored == nil {
+		return activityOverride{}, err
+	}
+	return activityOverride{eventId: stored.EventId, noSync: stored.NoSync}, nil
+}
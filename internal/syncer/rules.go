@@ -0,0 +1,105 @@
+package syncer
+
+import (
+	"regexp"
+	"strava-intervals-description-sync/internal/config"
+	"strava-intervals-description-sync/internal/intervals"
+	"strava-intervals-description-sync/internal/strava"
+	"strings"
+)
+
+// workoutMatcher is compiled config.WorkoutCriteria
+type workoutMatcher struct {
+	nameRegex *regexp.Regexp
+	sportType string
+	class     intervals.WorkoutClass
+}
+
+func newWorkoutMatcher(criteria config.WorkoutCriteria) (*workoutMatcher, error) {
+	m := &workoutMatcher{sportType: criteria.SportType, class: intervals.WorkoutClass(criteria.Class)}
+	if criteria.NameRegex != "" {
+		var err error
+		if m.nameRegex, err = regexp.Compile(criteria.NameRegex); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *workoutMatcher) matches(workout *intervals.Workout) bool {
+	if m.nameRegex != nil && !m.nameRegex.MatchString(workout.Name) {
+		return false
+	}
+	if m.sportType != "" && !strings.EqualFold(m.sportType, workout.Type) {
+		return false
+	}
+	if m.class != "" && m.class != workout.Class() {
+		return false
+	}
+	return true
+}
+
+type activityRule struct {
+	matcher *workoutMatcher
+	set     config.ActivityFields
+}
+
+func newActivityRules(cfg []config.ActivityRule) ([]*activityRule, error) {
+	rules := make([]*activityRule, 0, len(cfg))
+	for _, rule := range cfg {
+		matcher, err := newWorkoutMatcher(rule.Match)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &activityRule{matcher: matcher, set: rule.Set})
+	}
+	return rules, nil
+}
+
+// applyActivityRules sets fields of every rule matching the workout on activity, in order
+func applyActivityRules(rules []*activityRule, workout *intervals.Workout, activity *strava.UpdatableActivity) {
+	for _, rule := range rules {
+		if !rule.matcher.matches(workout) {
+			continue
+		}
+		if rule.set.SportType != "" {
+			activity.SportType = rule.set.SportType
+		}
+		if rule.set.Commute != nil {
+			activity.Commute = rule.set.Commute
+		}
+		if rule.set.Trainer != nil {
+			activity.Trainer = rule.set.Trainer
+		}
+		if rule.set.HideFromHome != nil {
+			activity.HideFromHome = rule.set.HideFromHome
+		}
+	}
+}
+
+type gearRule struct {
+	matcher *workoutMatcher
+	gearId  string
+}
+
+func newGearRules(cfg []config.GearRule) ([]*gearRule, error) {
+	rules := make([]*gearRule, 0, len(cfg))
+	for _, rule := range cfg {
+		matcher, err := newWorkoutMatcher(rule.Match)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &gearRule{matcher: matcher, gearId: rule.GearId})
+	}
+	return rules, nil
+}
+
+// matchGearRule returns gear id of the first rule matching the workout, or empty string if none matches
+func matchGearRule(rules []*gearRule, workout *intervals.Workout) string {
+	for _, rule := range rules {
+		if rule.matcher.matches(workout) {
+			return rule.gearId
+		}
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package syncer
+
+import (
+	"log"
+	"strava-intervals-description-sync/internal/config"
+	"strava-intervals-description-sync/internal/intervals"
+	"strings"
+	"unicode/utf8"
+)
+
+// summary renders the workout in configured notation followed by enabled wellness and fitness lines. Full notation
+// falls back to compact if the whole summary exceeds the length budget
+func (s *Syncer) summary(workout *intervals.Workout, activity *intervals.Activity,
+	sportSettings *intervals.AthleteSportSettings) string {
+	options := s.options
+	options.PoolLength = activity.PoolLength
+
+	var extras []string
+	if s.wellness.Enabled {
+		if wellnessSummary := s.wellnessSummary(activity); wellnessSummary != "" {
+			extras = append(extras, wellnessSummary)
+		}
+	}
+	if s.fitness.Enabled {
+		if fitnessSummary := intervals.GenerateFitnessSummary(s.fitness.Fields, activity); fitnessSummary != "" {
+			extras = append(extras, fitnessSummary)
+		}
+	}
+
+	workoutSummary := workout.Summary(sportSettings, options)
+	if s.notation == config.NotationCompact {
+		return joinSummary(render(intervals.CompactRenderer{}, workoutSummary), extras)
+	}
+
+	summary := joinSummary(render(intervals.TextRenderer{}, workoutSummary), extras)
+	if s.lengthBudget > 0 && utf8.RuneCountInString(summary) > s.lengthBudget {
+		log.Printf("Summary is longer than %d characters, using compact notation", s.lengthBudget)
+		summary = joinSummary(render(intervals.CompactRenderer{}, workoutSummary), extras)
+	}
+	return summary
+}
+
+// render renders with one of the text renderers, which never fail
+func render(renderer intervals.Renderer, summary *intervals.Summary) string {
+	text, _ := renderer.Render(summary)
+	return text
+}
+
+func joinSummary(workoutSummary string, extras []string) string {
+	parts := append([]string{workoutSummary}, extras...)
+	return strings.Join(parts, "\n\n")
+}
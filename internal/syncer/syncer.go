@@ -0,0 +1,331 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strava-intervals-description-sync/internal/config"
+	"strava-intervals-description-sync/internal/history"
+	"strava-intervals-description-sync/internal/intervals"
+	"strava-intervals-description-sync/internal/notify"
+	"strava-intervals-description-sync/internal/strava"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const SummarySeparator string = "---Workout Summary---"
+
+// Syncer appends intervals.icu workout summaries to Strava activities and records every attempt in history
+type Syncer struct {
+	strava    *strava.Client
+	intervals *intervals.Client
+	history   *history.Store
+	notifier  *notify.Notifier
+	titles    *titleRenderer
+	rules     []*activityRule
+	gearRules []*gearRule
+	wellness  config.WellnessConfig
+	fitness   config.FitnessConfig
+	options   intervals.DescriptionOptions
+	// notation and lengthBudget see config.SyncConfig
+	notation     string
+	lengthBudget int
+
+	stravaDestination    *destination
+	intervalsDestination *destination
+}
+
+func New(cfg config.SyncConfig, stravaClient *strava.Client, intervalsClient *intervals.Client,
+	historyStore *history.Store, notifier *notify.Notifier) (*Syncer, error) {
+	titles, err := newTitleRenderer(cfg.Title)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := newActivityRules(cfg.ActivityRules)
+	if err != nil {
+		return nil, err
+	}
+	gearRules, err := newGearRules(cfg.GearRules)
+	if err != nil {
+		return nil, err
+	}
+	stravaDestination, err := newDestination(cfg.Destinations.Strava)
+	if err != nil {
+		return nil, err
+	}
+	intervalsDestination, err := newDestination(cfg.Destinations.Intervals)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Syncer{
+		strava:    stravaClient,
+		intervals: intervalsClient,
+		history:   historyStore,
+		notifier:  notifier,
+		titles:    titles,
+		rules:     rules,
+		gearRules: gearRules,
+		wellness:  cfg.Wellness,
+		fitness:   cfg.Fitness,
+		options:   intervals.DescriptionOptions{ZoneLabels: cfg.ZoneLabels},
+
+		notation:     cfg.Notation,
+		lengthBudget: cfg.LengthBudget,
+
+		stravaDestination:    stravaDestination,
+		intervalsDestination: intervalsDestination,
+	}, nil
+}
+
+var (
+	errAlreadySynced = errors.New("activity already contains summary")
+	errNoSync        = errors.New("activity is excluded from sync")
+	// errUpToDate is returned for update webhooks that don't require a re-render, those aren't recorded in history
+	// as Strava sends one for every title change, including our own
+	errUpToDate = errors.New("activity summary is up to date")
+)
+
+// Sync finds the intervals.icu workout matching the newly created Strava activity and appends its summary to
+// activity's description
+func (s *Syncer) Sync(stravaActivityId int64) {
+	s.run(stravaActivityId, false)
+}
+
+// Resync handles an updated Strava activity, the summary is re-rendered only if the activity has a manual workout
+// override that differs from the last synced workout and the activity wasn't unsynced since
+func (s *Syncer) Resync(stravaActivityId int64) *history.Record {
+	return s.run(stravaActivityId, true)
+}
+
+func (s *Syncer) run(stravaActivityId int64, update bool) *history.Record {
+	record := &history.Record{
+		StravaActivityId: stravaActivityId,
+		Action:           history.ActionSync,
+		StartedAt:        time.Now(),
+	}
+
+	err := s.sync(record, update)
+	switch {
+	case errors.Is(err, errUpToDate):
+		log.Println("Activity summary is up to date")
+		return nil
+	case errors.Is(err, errAlreadySynced), errors.Is(err, errNoSync):
+		log.Println("Skipping activity", err)
+		record.Status = history.StatusSkipped
+		record.Error = err.Error()
+	case errors.Is(err, intervals.ErrNoWorkoutMatch):
+		log.Println("Skipping activity", err)
+		record.Status = history.StatusSkipped
+		record.Error = err.Error()
+	case err != nil:
+		record.Status = history.StatusFailed
+		record.Error = err.Error()
+		s.notifier.Notify(&notify.Notification{
+			Kind:        notify.KindSyncFailed,
+			Subject:     strconv.FormatInt(stravaActivityId, 10),
+			Title:       fmt.Sprintf("Sync of Strava activity %d failed", stravaActivityId),
+			Reason:      record.Error,
+			ActivityUrl: notify.StravaActivityUrl(stravaActivityId),
+		})
+	default:
+		record.Status = history.StatusSynced
+	}
+
+	record.FinishedAt = time.Now()
+	if err = s.history.Add(record); err != nil {
+		log.Println("Failed to write sync history", err)
+	}
+	return record
+}
+
+func (s *Syncer) sync(record *history.Record, update bool) error {
+	stravaActivity, err := s.strava.GetActivity(record.StravaActivityId)
+	if err != nil {
+		log.Println("Error getting strava activity ", err)
+		return err
+	}
+	record.DescriptionBefore = stravaActivity.Description
+	record.DescriptionAfter = stravaActivity.Description
+	record.NameBefore = stravaActivity.Name
+	record.NameAfter = stravaActivity.Name
+
+	override, err := s.override(stravaActivity)
+	if err != nil {
+		return err
+	}
+	if override.noSync {
+		return errNoSync
+	}
+
+	hasSummary := strings.Contains(stravaActivity.Description, SummarySeparator)
+	if update {
+		if override.eventId == 0 {
+			return errUpToDate
+		}
+		// unsync restoring the title triggers an update webhook itself, which must not add the summary back
+		latest, err := s.history.Latest(record.StravaActivityId, "")
+		if err != nil {
+			return err
+		}
+		if latest != nil && latest.Action == history.ActionUnsync {
+			return errUpToDate
+		}
+		lastSync, err := s.history.Latest(record.StravaActivityId, history.StatusSynced)
+		if err != nil {
+			return err
+		}
+		if hasSummary && lastSync != nil && lastSync.WorkoutId == override.eventId {
+			return errUpToDate
+		}
+	} else if s.stravaDestination != nil && hasSummary {
+		return errAlreadySynced
+	}
+	// re-rendering replaces the previous summary
+	baseDescription := StripSummary(stravaActivity.Description)
+
+	loc := s.activityLocation(stravaActivity)
+	from := stravaActivity.StartDate.In(loc).Add(-1 * time.Hour)
+	to := stravaActivity.StartDate.In(loc).Add(time.Hour)
+
+	// activities uploaded to intervals.icu from the device directly have no strava_id, those are matched by attributes
+	query := &intervals.ActivityQuery{
+		StravaId:    record.StravaActivityId,
+		Start:       stravaActivity.StartDate,
+		ElapsedTime: float32(stravaActivity.ElapsedTime),
+		Distance:    stravaActivity.Distance,
+		SportType:   stravaActivity.SportType,
+	}
+
+	// updated activities have been processed by intervals.icu long ago, there's no webhook to wait for
+	var activityMatch *intervals.ActivityMatch
+	if update {
+		activityMatch, err = s.intervals.FindActivity(query, &from, &to)
+	} else {
+		activityMatch, err = s.intervals.AwaitActivity(query, &from, &to)
+	}
+	if err != nil {
+		log.Println("Error getting intervals activity ", err)
+		return err
+	}
+	intervalsActivity := activityMatch.Activity
+	record.IntervalsActivityId = intervalsActivity.Id
+	record.ActivityMatchStrategy = string(activityMatch.Strategy)
+
+	var workoutMatch *intervals.WorkoutMatch
+	if override.eventId != 0 {
+		workout, err := s.intervals.GetEvent(override.eventId)
+		if err != nil {
+			log.Println("Error getting overridden intervals workout ", err)
+			return err
+		}
+		workoutMatch = &intervals.WorkoutMatch{Workout: workout, Strategy: intervals.MatchStrategyManual, Confidence: 1}
+	} else if workoutMatch, err = s.intervals.FindWorkoutForActivity(intervalsActivity, loc); err != nil {
+		log.Println("Error getting intervals workout ", err)
+		return err
+	}
+	record.WorkoutId = workoutMatch.Workout.Id
+	record.WorkoutMatchStrategy = string(workoutMatch.Strategy)
+	record.WorkoutMatchConfidence = workoutMatch.Confidence
+
+	athleteSportSettings, err := s.intervals.GetWorkoutSportSettings(workoutMatch.Workout)
+	if err != nil {
+		log.Println("Error getting athleteSportSettings ", err)
+		return err
+	}
+
+	workoutSummary := s.summary(workoutMatch.Workout, intervalsActivity, athleteSportSettings)
+
+	updatableActivity := &strava.UpdatableActivity{Description: stravaActivity.Description}
+	if s.stravaDestination != nil {
+		stravaSummary, err := s.stravaDestination.render(workoutSummary, workoutMatch.Workout)
+		if err != nil {
+			log.Println("Error rendering strava summary ", err)
+			return err
+		}
+		updatableActivity.Description = appendSummary(baseDescription, stravaSummary)
+	}
+
+	if updatableActivity.Name, err = s.titles.title(stravaActivity, workoutMatch.Workout); err != nil {
+		log.Println("Failed to render activity title, keeping the current one", err)
+	}
+	applyActivityRules(s.rules, workoutMatch.Workout, updatableActivity)
+	updatableActivity.GearId = s.gearFor(stravaActivity, workoutMatch.Workout)
+
+	if err = s.strava.UpdateActivity(record.StravaActivityId, updatableActivity); err != nil {
+		log.Println("Error updating strava activity ", err)
+		return err
+	}
+	record.DescriptionAfter = updatableActivity.Description
+	if updatableActivity.Name != "" {
+		record.NameAfter = updatableActivity.Name
+	}
+
+	// intervals.icu write-back failing doesn't undo the Strava update, so it's only recorded as an error of a
+	// successful sync
+	if s.intervalsDestination != nil {
+		if err = s.writeToIntervals(record, intervalsActivity, workoutSummary, workoutMatch.Workout); err != nil {
+			log.Println("Error writing summary to intervals activity ", err)
+			record.Error = "intervals.icu write-back failed: " + err.Error()
+		}
+	}
+
+	return nil
+}
+
+// gearFor returns the gear id to assign to the activity, or empty string if gear shouldn't change because no rule
+// matched or the activity's gear was changed from athlete's default by hand
+func (s *Syncer) gearFor(activity *strava.Activity, workout *intervals.Workout) string {
+	gearId := matchGearRule(s.gearRules, workout)
+	if gearId == "" || gearId == activity.GearId {
+		return ""
+	}
+
+	if activity.GearId != "" {
+		athlete, err := s.strava.GetAthlete()
+		if err != nil {
+			log.Println("Failed to get athlete gear, not changing gear", err)
+			return ""
+		}
+		// activity's gear is always one of athlete's, so no gear at all means the token lacks `profile:read_all`
+		if !athlete.HasGear() {
+			log.Println("Strava returned no athlete gear, re-authorization is needed for gear rules")
+			s.notifier.Notify(&notify.Notification{
+				Kind:    notify.KindStravaToken,
+				Subject: "gear",
+				Title:   "Strava re-authorization needed for gear rules",
+				Reason: fmt.Sprintf("Strava doesn't return athlete's gear for the current token, gear rules aren't "+
+					"applied until the app is re-authorized at %s", s.strava.AuthenticationUrl()),
+				ActivityUrl: notify.StravaActivityUrl(activity.Id),
+			})
+			return ""
+		}
+		if !athlete.IsDefaultGear(activity.GearId) {
+			log.Println("Activity gear is not athlete's default, not changing gear")
+			return ""
+		}
+	}
+
+	log.Printf("Changing activity gear from %q to %q", activity.GearId, gearId)
+	return gearId
+}
+
+// activityLocation returns the timezone of the activity from Strava, falling back to intervals.icu athlete's
+// timezone and UTC. Date windows have to be in it, otherwise early or late activities end up on the wrong day
+func (s *Syncer) activityLocation(activity *strava.Activity) *time.Location {
+	loc, err := activity.Location()
+	if err == nil {
+		return loc
+	}
+	log.Printf("Failed to get timezone of activity %d (%v), using intervals.icu athlete timezone", activity.Id, err)
+
+	athlete, err := s.intervals.GetAthlete()
+	if err == nil {
+		if loc, err = time.LoadLocation(athlete.Timezone); err == nil {
+			return loc
+		}
+	}
+	log.Println("Failed to get intervals.icu athlete timezone, using UTC", err)
+	return time.UTC
+}
@@ -0,0 +1,58 @@
+package syncer
+
+import (
+	"bytes"
+	"regexp"
+	"strava-intervals-description-sync/internal/config"
+	"strava-intervals-description-sync/internal/intervals"
+	"strava-intervals-description-sync/internal/strava"
+	"strings"
+	"text/template"
+)
+
+// titleRenderer builds activity titles from the matched workout, it's nil when title sync is disabled
+type titleRenderer struct {
+	defaultPattern *regexp.Regexp
+	template       *template.Template
+}
+
+type titleTemplateData struct {
+	Workout  *intervals.Workout
+	Activity *strava.Activity
+}
+
+func newTitleRenderer(cfg config.TitleConfig) (*titleRenderer, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	defaultPattern, err := regexp.Compile(cfg.DefaultPattern)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New("title").Parse(cfg.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	return &titleRenderer{defaultPattern: defaultPattern, template: tmpl}, nil
+}
+
+// title returns the new activity title, or empty string if the current one was set by hand or the template
+// rendered nothing
+func (r *titleRenderer) title(activity *strava.Activity, workout *intervals.Workout) (string, error) {
+	if r == nil || !r.defaultPattern.MatchString(activity.Name) {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	if err := r.template.Execute(&buf, titleTemplateData{Workout: workout, Activity: activity}); err != nil {
+		return "", err
+	}
+
+	title := strings.TrimSpace(buf.String())
+	if title == activity.Name {
+		return "", nil
+	}
+	return title, nil
+}
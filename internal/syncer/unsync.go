@@ -0,0 +1,160 @@
+package syncer
+
+import (
+	"log"
+	"strava-intervals-description-sync/internal/history"
+	"strava-intervals-description-sync/internal/strava"
+	"strings"
+	"time"
+)
+
+type UnsyncMethod string
+
+const (
+	// UnsyncMethodHistory restores the exact description recorded before the last sync
+	UnsyncMethodHistory UnsyncMethod = "history"
+	// UnsyncMethodStrip cuts the description at SummarySeparator, used when there's no usable history
+	UnsyncMethodStrip UnsyncMethod = "strip"
+	// UnsyncMethodNone means the activity doesn't contain a summary
+	UnsyncMethodNone UnsyncMethod = "none"
+)
+
+type UnsyncResult struct {
+	StravaActivityId  int64        `json:"strava_activity_id"`
+	Method            UnsyncMethod `json:"method"`
+	DescriptionBefore string       `json:"description_before"`
+	DescriptionAfter  string       `json:"description_after"`
+	NameBefore        string       `json:"name_before"`
+	NameAfter         string       `json:"name_after"`
+	DryRun            bool         `json:"dry_run"`
+	Error             string       `json:"error,omitempty"`
+}
+
+// Unsync removes the workout summary from the activity's description. If history contains the last sync of this
+// activity and the description hasn't been edited since, the pre-sync description is restored exactly, otherwise
+// everything from SummarySeparator onwards is removed. A title replaced by a sync is restored unless it was renamed
+// since. With dryRun nothing is written to Strava or history
+func (s *Syncer) Unsync(stravaActivityId int64, dryRun bool) (*UnsyncResult, error) {
+	startedAt := time.Now()
+	result := &UnsyncResult{StravaActivityId: stravaActivityId, DryRun: dryRun}
+
+	stravaActivity, err := s.strava.GetActivity(stravaActivityId)
+	if err != nil {
+		return nil, err
+	}
+	result.DescriptionBefore = stravaActivity.Description
+	result.DescriptionAfter, result.Method = s.unsyncedDescription(stravaActivityId, stravaActivity.Description)
+	result.NameBefore = stravaActivity.Name
+	result.NameAfter = s.unsyncedName(stravaActivityId, stravaActivity.Name)
+
+	if dryRun {
+		return result, nil
+	}
+
+	record := &history.Record{
+		StravaActivityId:  stravaActivityId,
+		Action:            history.ActionUnsync,
+		Status:            history.StatusUnsynced,
+		DescriptionBefore: result.DescriptionBefore,
+		DescriptionAfter:  result.DescriptionAfter,
+		NameBefore:        result.NameBefore,
+		NameAfter:         result.NameAfter,
+		StartedAt:         startedAt,
+	}
+
+	updatableActivity := &strava.UpdatableActivity{Description: result.DescriptionAfter}
+	if result.NameAfter != result.NameBefore {
+		updatableActivity.Name = result.NameAfter
+	}
+
+	if !result.Changed() {
+		record.Status = history.StatusSkipped
+	} else if err = s.strava.UpdateActivity(stravaActivityId, updatableActivity); err != nil {
+		record.Status = history.StatusFailed
+		record.Error = err.Error()
+		record.DescriptionAfter = result.DescriptionBefore
+		record.NameAfter = result.NameBefore
+	}
+
+	record.FinishedAt = time.Now()
+	if historyErr := s.history.Add(record); historyErr != nil {
+		log.Println("Failed to write unsync history", historyErr)
+	}
+
+	return result, err
+}
+
+// Changed reports whether unsync has anything to write, the summary or a replaced title
+func (r *UnsyncResult) Changed() bool {
+	return r.Method != UnsyncMethodNone || r.NameAfter != r.NameBefore
+}
+
+// UnsyncRange runs Unsync for every activity that started within [from, to), failures of single activities are
+// reported in their result and don't stop the rest
+func (s *Syncer) UnsyncRange(from time.Time, to time.Time, dryRun bool) ([]*UnsyncResult, error) {
+	activities, err := s.strava.ListActivities(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*UnsyncResult, 0, len(activities))
+	for _, activity := range activities {
+		result, err := s.Unsync(activity.Id, dryRun)
+		if err != nil {
+			log.Printf("Failed to unsync activity %d: %v", activity.Id, err)
+			if result == nil {
+				result = &UnsyncResult{StravaActivityId: activity.Id, DryRun: dryRun}
+			}
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (s *Syncer) unsyncedDescription(stravaActivityId int64, description string) (string, UnsyncMethod) {
+	if !strings.Contains(description, SummarySeparator) {
+		return description, UnsyncMethodNone
+	}
+
+	lastSync, err := s.history.Latest(stravaActivityId, history.StatusSynced)
+	if err != nil {
+		log.Println("Failed to read sync history, falling back to stripping summary", err)
+	}
+	if lastSync != nil && lastSync.DescriptionAfter == description {
+		// a re-rendered sync started from a description that already had a summary
+		return StripSummary(lastSync.DescriptionBefore), UnsyncMethodHistory
+	}
+
+	return StripSummary(description), UnsyncMethodStrip
+}
+
+// unsyncedName returns the title from before the latest sync that replaced it, if the activity still has the title that
+// sync set. Syncs that kept the title (e.g. re-syncs of an already renamed activity) are skipped
+func (s *Syncer) unsyncedName(stravaActivityId int64, name string) string {
+	syncs, err := s.history.List(history.Query{StravaActivityId: stravaActivityId, Status: history.StatusSynced})
+	if err != nil {
+		log.Println("Failed to read sync history, keeping title", err)
+		return name
+	}
+	for _, sync := range syncs {
+		if sync.NameBefore == sync.NameAfter {
+			continue
+		}
+		if sync.NameAfter == name && sync.NameBefore != "" {
+			return sync.NameBefore
+		}
+		break
+	}
+	return name
+}
+
+// StripSummary returns description without SummarySeparator and everything after it
+func StripSummary(description string) string {
+	before, _, found := strings.Cut(description, SummarySeparator)
+	if !found {
+		return description
+	}
+	return strings.TrimSuffix(before, "\n")
+}
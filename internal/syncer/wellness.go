@@ -0,0 +1,16 @@
+package syncer
+
+import (
+	"log"
+	"strava-intervals-description-sync/internal/intervals"
+)
+
+// wellnessSummary renders the wellness line for the activity's day, failing to fetch wellness only leaves out the
+// wellness data
+func (s *Syncer) wellnessSummary(activity *intervals.Activity) string {
+	wellness, err := s.intervals.GetWellness(activity.StartDateLocal.Time)
+	if err != nil {
+		log.Println("Failed to get intervals wellness, leaving it out of the summary", err)
+	}
+	return intervals.GenerateWellnessSummary(s.wellness.Fields, wellness, activity)
+}
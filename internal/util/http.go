@@ -1,10 +1,12 @@
 package util
 
 import (
+	"crypto/subtle"
 	"errors"
 	"log"
 	"math"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -22,6 +24,10 @@ func SendHttpRequestWithExpRetry(sendRequest func() (*http.Response, error),
 			return resp, err
 		}
 
+		// the response is discarded, its connection can only be reused once the body is closed
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
 		if err = beforeRetry(resp, err); err != nil {
 			log.Printf("Before retry failed: %v", err)
 			return nil, err
@@ -34,3 +40,15 @@ func SendHttpRequestWithExpRetry(sendRequest func() (*http.Response, error),
 
 	return nil, errors.New("max retry exceeded")
 }
+
+// RequireBearerToken only passes requests carrying `Authorization: Bearer <token>` to next
+func RequireBearerToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		provided, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next(w, req)
+	}
+}
@@ -0,0 +1,84 @@
+package workoutfile
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strava-intervals-description-sync/internal/intervals"
+	"strconv"
+)
+
+const ExportUrl string = "/admin/workouts/{eventId}/export"
+
+// Exporter exports planned workouts of the intervals.icu calendar
+type Exporter struct {
+	intervals *intervals.Client
+}
+
+func NewExporter(intervals *intervals.Client) *Exporter {
+	return &Exporter{intervals: intervals}
+}
+
+// Fetch fetches the calendar event and sport settings of its sport needed to resolve targets
+func (e *Exporter) Fetch(eventId int) (*intervals.Workout, *intervals.AthleteSportSettings, error) {
+	workout, err := e.intervals.GetEvent(eventId)
+	if err != nil {
+		log.Println("Failed to fetch intervals event", err)
+		return nil, nil, err
+	}
+	sportSettings, err := e.intervals.GetWorkoutSportSettings(workout)
+	if err != nil {
+		log.Println("Failed to fetch intervals sport settings", err)
+		return nil, nil, err
+	}
+	return workout, sportSettings, nil
+}
+
+// ExportEvent converts the calendar event to a workout file, returns its content and file name
+func (e *Exporter) ExportEvent(eventId int, format string) ([]byte, string, error) {
+	workout, sportSettings, err := e.Fetch(eventId)
+	if err != nil {
+		return nil, "", err
+	}
+	content, err := Export(workout, sportSettings, format)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to export event %d: %w", eventId, err)
+	}
+	return content, FileName(workout, format), nil
+}
+
+// HandleExport responds with the calendar event as a workout file in `format` query param (zwo, erg, mrc or fit,
+// default zwo)
+func (e *Exporter) HandleExport(w http.ResponseWriter, req *http.Request) {
+	eventId, err := strconv.Atoi(req.PathValue("eventId"))
+	if err != nil {
+		http.Error(w, "invalid event id", http.StatusBadRequest)
+		return
+	}
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = FormatZwo
+	}
+	if !slices.Contains(Formats, format) {
+		http.Error(w, "invalid format", http.StatusBadRequest)
+		return
+	}
+
+	workout, sportSettings, err := e.Fetch(eventId)
+	if err != nil {
+		http.Error(w, "failed to fetch event", http.StatusBadGateway)
+		return
+	}
+	content, err := Export(workout, sportSettings, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", FileName(workout, format)))
+	if _, err = w.Write(content); err != nil {
+		log.Println("Failed to write response", err)
+	}
+}
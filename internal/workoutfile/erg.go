@@ -0,0 +1,60 @@
+package workoutfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strava-intervals-description-sync/internal/intervals"
+)
+
+// exportErg writes an ERG (watts) or MRC (percent of FTP) course, a power point at the start and end of every step so
+// ramps are preserved. Repeats are unrolled as the format has none
+func exportErg(workout *intervals.Workout, sportSettings *intervals.AthleteSportSettings, percent bool) ([]byte, error) {
+	if sportSettings.Ftp <= 0 {
+		return nil, errors.New("ftp is missing from sport settings")
+	}
+
+	var data bytes.Buffer
+	var minutes float64
+	for i, step := range flatten(*workout.WorkoutDoc.Steps) {
+		target, ok := step.AbsoluteTarget(sportSettings)
+		if !ok || target.Kind != intervals.TargetPower {
+			return nil, stepError(i, step, "ERG/MRC files need a power target")
+		}
+		if step.Duration <= 0 {
+			return nil, stepError(i, step, "ERG/MRC files need a duration")
+		}
+
+		start, end := float64(target.Start), float64(target.End)
+		if !step.Ramp {
+			// ranges are held at their middle
+			start = (start + end) / 2
+			end = start
+		}
+		if percent {
+			start, end = start/float64(sportSettings.Ftp)*100, end/float64(sportSettings.Ftp)*100
+		}
+
+		fmt.Fprintf(&data, "%.2f\t%.0f\n", minutes, start)
+		minutes += float64(step.Duration) / 60
+		fmt.Fprintf(&data, "%.2f\t%.0f\n", minutes, end)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("[COURSE HEADER]\n")
+	b.WriteString("VERSION = 2\n")
+	b.WriteString("UNITS = ENGLISH\n")
+	fmt.Fprintf(&b, "DESCRIPTION = %s\n", workout.Name)
+	fmt.Fprintf(&b, "FILE NAME = %s\n", workout.Name)
+	if percent {
+		b.WriteString("MINUTES PERCENT\n")
+	} else {
+		fmt.Fprintf(&b, "FTP = %d\n", sportSettings.Ftp)
+		b.WriteString("MINUTES WATTS\n")
+	}
+	b.WriteString("[END COURSE HEADER]\n")
+	b.WriteString("[COURSE DATA]\n")
+	b.Write(data.Bytes())
+	b.WriteString("[END COURSE DATA]\n")
+	return b.Bytes(), nil
+}
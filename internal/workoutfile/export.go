@@ -0,0 +1,99 @@
+package workoutfile
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strava-intervals-description-sync/internal/intervals"
+	"strings"
+)
+
+const (
+	// FormatZwo is a Zwift workout
+	FormatZwo = "zwo"
+	// FormatErg and FormatMrc are power based trainer workouts in watts and percent of FTP
+	FormatErg = "erg"
+	FormatMrc = "mrc"
+	// FormatFit is a Garmin FIT workout file
+	FormatFit = "fit"
+)
+
+// Formats lists formats accepted by Export
+var Formats = []string{FormatZwo, FormatErg, FormatMrc, FormatFit}
+
+var errNoSteps = errors.New("workout has no steps")
+
+// Export converts the workout to a workout file in format, targets are resolved to absolute values using sport
+// settings
+func Export(workout *intervals.Workout, sportSettings *intervals.AthleteSportSettings, format string) ([]byte, error) {
+	if workout.WorkoutDoc == nil || workout.WorkoutDoc.Steps == nil || len(*workout.WorkoutDoc.Steps) == 0 {
+		return nil, errNoSteps
+	}
+	if sportSettings == nil {
+		sportSettings = &intervals.AthleteSportSettings{}
+	}
+
+	switch format {
+	case FormatZwo:
+		return exportZwo(workout, sportSettings)
+	case FormatErg:
+		return exportErg(workout, sportSettings, false)
+	case FormatMrc:
+		return exportErg(workout, sportSettings, true)
+	case FormatFit:
+		return exportFit(workout, sportSettings)
+	default:
+		return nil, fmt.Errorf("unknown workout file format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// FileName is a file system safe name for the exported workout, e.g. `5x3m_Threshold.zwo`
+func FileName(workout *intervals.Workout, format string) string {
+	name := strings.Trim(unsafeFileNameChars.ReplaceAllString(workout.Name, "_"), "_")
+	if name == "" {
+		name = fmt.Sprintf("workout_%d", workout.Id)
+	}
+	return name + "." + format
+}
+
+// ContentType is the http content type of format
+func ContentType(format string) string {
+	switch format {
+	case FormatZwo:
+		return "application/xml"
+	case FormatFit:
+		return "application/vnd.ant.fit"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// flatten unrolls repeats into a flat list of steps, for formats without repeats
+func flatten(steps []intervals.WorkoutStep) []intervals.WorkoutStep {
+	var flat []intervals.WorkoutStep
+	for _, step := range steps {
+		if isRepeat(step) {
+			children := flatten(*step.Steps)
+			for i := 0; i < step.Repetitions; i++ {
+				flat = append(flat, children...)
+			}
+			continue
+		}
+		flat = append(flat, step)
+	}
+	return flat
+}
+
+func isRepeat(step intervals.WorkoutStep) bool {
+	return step.Repetitions > 0 && step.Steps != nil && len(*step.Steps) > 0
+}
+
+// stepError points to the offending step by 0-based index, shown numbered from 1
+func stepError(index int, step intervals.WorkoutStep, reason string) error {
+	if step.Text != "" {
+		return fmt.Errorf("step %d (%s): %s", index+1, step.Text, reason)
+	}
+	return fmt.Errorf("step %d: %s", index+1, reason)
+}
@@ -0,0 +1,256 @@
+package workoutfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strava-intervals-description-sync/internal/intervals"
+	"time"
+	"unicode/utf8"
+)
+
+// FIT profile constants, see the FIT SDK Profile.xlsx
+const (
+	fitProtocolVersion = 0x20
+	fitProfileVersion  = 2132
+	// fitEpoch is 1989-12-31T00:00:00Z, FIT timestamps are seconds since then
+	fitEpoch = 631065600
+
+	fitMesgFileId      = 0
+	fitMesgWorkout     = 26
+	fitMesgWorkoutStep = 27
+
+	fitBaseEnum    = 0x00
+	fitBaseString  = 0x07
+	fitBaseUint16  = 0x84
+	fitBaseUint32  = 0x86
+	fitBaseUint32z = 0x8c
+
+	fitFileWorkout             = 5
+	fitManufacturerDevelopment = 255
+
+	fitDurationTime     = 0
+	fitDurationDistance = 1
+	fitDurationOpen     = 5
+	fitDurationRepeat   = 6
+
+	fitTargetSpeed     = 0
+	fitTargetHeartRate = 1
+	fitTargetOpen      = 2
+	fitTargetPower     = 4
+
+	fitIntensityActive   = 0
+	fitIntensityWarmup   = 2
+	fitIntensityCooldown = 3
+
+	fitInvalidUint32 = math.MaxUint32
+	// fitNameSize is the fixed size of name fields including the terminating zero
+	fitNameSize = 32
+)
+
+var fitSports = map[intervals.SportType]uint8{intervals.SportTypeRun: 1, intervals.SportTypeRide: 2, intervals.SportTypeSwim: 5}
+
+type fitField struct {
+	num      uint8
+	size     uint8
+	baseType uint8
+}
+
+type fitWorkoutStep struct {
+	name          string
+	durationType  uint8
+	durationValue uint32
+	targetType    uint8
+	targetValue   uint32
+	low, high     uint32
+	intensity     uint8
+}
+
+// fitEncoder writes FIT records, local message types are numbered by the order of definitions
+type fitEncoder struct {
+	data bytes.Buffer
+}
+
+func (e *fitEncoder) define(local uint8, mesg uint16, fields []fitField) {
+	e.data.WriteByte(0x40 | local)
+	// reserved, little endian architecture
+	e.data.Write([]byte{0, 0})
+	_ = binary.Write(&e.data, binary.LittleEndian, mesg)
+	e.data.WriteByte(uint8(len(fields)))
+	for _, field := range fields {
+		e.data.Write([]byte{field.num, field.size, field.baseType})
+	}
+}
+
+// write writes a data message, values are written in the order of the definition
+func (e *fitEncoder) write(local uint8, values ...any) {
+	e.data.WriteByte(local)
+	for _, value := range values {
+		if text, ok := value.(string); ok {
+			e.data.Write(fitString(text))
+			continue
+		}
+		_ = binary.Write(&e.data, binary.LittleEndian, value)
+	}
+}
+
+func (e *fitEncoder) bytes() []byte {
+	header := make([]byte, 14)
+	header[0] = 14
+	header[1] = fitProtocolVersion
+	binary.LittleEndian.PutUint16(header[2:], fitProfileVersion)
+	binary.LittleEndian.PutUint32(header[4:], uint32(e.data.Len()))
+	copy(header[8:], ".FIT")
+	binary.LittleEndian.PutUint16(header[12:], fitCrc(0, header[:12]))
+
+	file := append(header, e.data.Bytes()...)
+	return binary.LittleEndian.AppendUint16(file, fitCrc(0, file))
+}
+
+// fitString is a zero terminated and padded name, truncated at a rune boundary
+func fitString(text string) []byte {
+	b := make([]byte, fitNameSize)
+	n := 0
+	for _, r := range text {
+		size := utf8.RuneLen(r)
+		if size < 0 || n+size > fitNameSize-1 {
+			break
+		}
+		n += utf8.EncodeRune(b[n:], r)
+	}
+	return b
+}
+
+var fitCrcTable = [16]uint16{
+	0x0000, 0xcc01, 0xd801, 0x1400, 0xf001, 0x3c00, 0x2800, 0xe401,
+	0xa001, 0x6c00, 0x7800, 0xb401, 0x5000, 0x9c01, 0x8801, 0x4400,
+}
+
+func fitCrc(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		tmp := fitCrcTable[crc&0xf]
+		crc = (crc >> 4) & 0x0fff
+		crc = crc ^ tmp ^ fitCrcTable[b&0xf]
+
+		tmp = fitCrcTable[crc&0xf]
+		crc = (crc >> 4) & 0x0fff
+		crc = crc ^ tmp ^ fitCrcTable[(b>>4)&0xf]
+	}
+	return crc
+}
+
+// exportFit writes a FIT workout file. Repeats are kept as repeat steps (nested ones included), targets are custom
+// ranges in absolute values and ramps become the range they go through
+func exportFit(workout *intervals.Workout, sportSettings *intervals.AthleteSportSettings) ([]byte, error) {
+	var steps []*fitWorkoutStep
+	fitSteps(*workout.WorkoutDoc.Steps, sportSettings, &steps)
+
+	e := &fitEncoder{}
+	e.define(0, fitMesgFileId, []fitField{
+		{0, 1, fitBaseEnum},    // type
+		{1, 2, fitBaseUint16},  // manufacturer
+		{2, 2, fitBaseUint16},  // product
+		{3, 4, fitBaseUint32z}, // serial_number
+		{4, 4, fitBaseUint32},  // time_created
+	})
+	serial := uint32(workout.Id)
+	if serial == 0 {
+		serial = 1
+	}
+	e.write(0, uint8(fitFileWorkout), uint16(fitManufacturerDevelopment), uint16(0), serial,
+		uint32(time.Now().Unix()-fitEpoch))
+
+	e.define(1, fitMesgWorkout, []fitField{
+		{4, 1, fitBaseEnum},             // sport
+		{6, 2, fitBaseUint16},           // num_valid_steps
+		{8, fitNameSize, fitBaseString}, // wkt_name
+	})
+	e.write(1, fitSports[intervals.SportSettingsType(workout.Type)], uint16(len(steps)), workout.Name)
+
+	e.define(2, fitMesgWorkoutStep, []fitField{
+		{254, 2, fitBaseUint16},         // message_index
+		{0, fitNameSize, fitBaseString}, // wkt_step_name
+		{1, 1, fitBaseEnum},             // duration_type
+		{2, 4, fitBaseUint32},           // duration_value
+		{3, 1, fitBaseEnum},             // target_type
+		{4, 4, fitBaseUint32},           // target_value
+		{5, 4, fitBaseUint32},           // custom_target_value_low
+		{6, 4, fitBaseUint32},           // custom_target_value_high
+		{7, 1, fitBaseEnum},             // intensity
+	})
+	for i, step := range steps {
+		e.write(2, uint16(i), step.name, step.durationType, step.durationValue, step.targetType, step.targetValue,
+			step.low, step.high, step.intensity)
+	}
+
+	return e.bytes(), nil
+}
+
+// fitSteps appends steps in FIT order, a repeat step follows its children and points back to the first one
+func fitSteps(steps []intervals.WorkoutStep, sportSettings *intervals.AthleteSportSettings, out *[]*fitWorkoutStep) {
+	for _, step := range steps {
+		if isRepeat(step) {
+			first := len(*out)
+			fitSteps(*step.Steps, sportSettings, out)
+			*out = append(*out, &fitWorkoutStep{
+				durationType:  fitDurationRepeat,
+				durationValue: uint32(first),
+				targetType:    fitTargetOpen,
+				targetValue:   uint32(step.Repetitions),
+				low:           fitInvalidUint32,
+				high:          fitInvalidUint32,
+				intensity:     fitIntensityActive,
+			})
+			continue
+		}
+		*out = append(*out, newFitWorkoutStep(step, sportSettings))
+	}
+}
+
+func newFitWorkoutStep(step intervals.WorkoutStep, sportSettings *intervals.AthleteSportSettings) *fitWorkoutStep {
+	fitStep := &fitWorkoutStep{
+		name:       step.Text,
+		targetType: fitTargetOpen,
+		low:        fitInvalidUint32,
+		high:       fitInvalidUint32,
+		intensity:  fitIntensityActive,
+	}
+
+	switch {
+	case step.Distance > 0:
+		fitStep.durationType = fitDurationDistance
+		fitStep.durationValue = uint32(math.Round(float64(step.Distance) * 100))
+	case step.Duration > 0:
+		fitStep.durationType = fitDurationTime
+		fitStep.durationValue = uint32(math.Round(float64(step.Duration) * 1000))
+	default:
+		fitStep.durationType = fitDurationOpen
+	}
+
+	switch {
+	case step.Warmup:
+		fitStep.intensity = fitIntensityWarmup
+	case step.Cooldown:
+		fitStep.intensity = fitIntensityCooldown
+	}
+
+	target, ok := step.AbsoluteTarget(sportSettings)
+	if !ok {
+		return fitStep
+	}
+	low, high := float64(min(target.Start, target.End)), float64(max(target.Start, target.End))
+	switch target.Kind {
+	case intervals.TargetHeartRate:
+		// values up to 100 are percentages of max hr, absolute bpm are offset by 100
+		fitStep.targetType = fitTargetHeartRate
+		fitStep.low, fitStep.high = uint32(math.Round(low))+100, uint32(math.Round(high))+100
+	case intervals.TargetPace:
+		fitStep.targetType = fitTargetSpeed
+		fitStep.low, fitStep.high = uint32(math.Round(low*1000)), uint32(math.Round(high*1000))
+	case intervals.TargetPower:
+		// values up to 1000 are percentages of FTP, absolute watts are offset by 1000
+		fitStep.targetType = fitTargetPower
+		fitStep.low, fitStep.high = uint32(math.Round(low))+1000, uint32(math.Round(high))+1000
+	}
+	return fitStep
+}
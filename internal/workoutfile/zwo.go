@@ -0,0 +1,176 @@
+package workoutfile
+
+import (
+	"encoding/xml"
+	"math"
+	"strava-intervals-description-sync/internal/intervals"
+)
+
+type zwoFile struct {
+	XMLName     xml.Name `xml:"workout_file"`
+	Author      string   `xml:"author"`
+	Name        string   `xml:"name"`
+	Description string   `xml:"description"`
+	SportType   string   `xml:"sportType"`
+	Steps       []any    `xml:"workout>step"`
+}
+
+// zwoRamp is used for Warmup, Cooldown and Ramp elements, power goes from PowerLow to PowerHigh
+type zwoRamp struct {
+	XMLName   xml.Name
+	Duration  int     `xml:"Duration,attr"`
+	PowerLow  float64 `xml:"PowerLow,attr"`
+	PowerHigh float64 `xml:"PowerHigh,attr"`
+}
+
+type zwoSteadyState struct {
+	XMLName  xml.Name `xml:"SteadyState"`
+	Duration int      `xml:"Duration,attr"`
+	Power    float64  `xml:"Power,attr"`
+}
+
+type zwoIntervals struct {
+	XMLName     xml.Name `xml:"IntervalsT"`
+	Repeat      int      `xml:"Repeat,attr"`
+	OnDuration  int      `xml:"OnDuration,attr"`
+	OffDuration int      `xml:"OffDuration,attr"`
+	OnPower     float64  `xml:"OnPower,attr"`
+	OffPower    float64  `xml:"OffPower,attr"`
+}
+
+type zwoFreeRide struct {
+	XMLName  xml.Name `xml:"FreeRide"`
+	Duration int      `xml:"Duration,attr"`
+}
+
+// zwoStep is a non repeat step resolved to fraction of FTP (bike) or threshold pace (run)
+type zwoStep struct {
+	duration   int
+	start, end float64
+	free       bool
+	ramp       bool
+}
+
+// exportZwo writes a Zwift workout. Zwift only knows power (or pace for runs) relative to the threshold, heart rate
+// steps become free ride. Repeats of two steps are kept as IntervalsT, other repeats are unrolled
+func exportZwo(workout *intervals.Workout, sportSettings *intervals.AthleteSportSettings) ([]byte, error) {
+	sportType := "bike"
+	if intervals.SportSettingsType(workout.Type) == intervals.SportTypeRun {
+		sportType = "run"
+	}
+
+	file := &zwoFile{Name: workout.Name, Description: workout.Name, SportType: sportType}
+	counter := 0
+	steps, err := zwoSteps(*workout.WorkoutDoc.Steps, sportSettings, &counter)
+	if err != nil {
+		return nil, err
+	}
+	file.Steps = steps
+
+	out, err := xml.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+func zwoSteps(steps []intervals.WorkoutStep, sportSettings *intervals.AthleteSportSettings, counter *int) ([]any, error) {
+	var elements []any
+	for _, step := range steps {
+		if !isRepeat(step) {
+			resolved, err := newZwoStep(step, sportSettings, counter)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, resolved.element(step))
+			continue
+		}
+
+		// every repetition refers to the same steps in errors
+		start := *counter
+		children := *step.Steps
+		if len(children) == 2 && !isRepeat(children[0]) && !isRepeat(children[1]) {
+			on, err := newZwoStep(children[0], sportSettings, counter)
+			if err != nil {
+				return nil, err
+			}
+			off, err := newZwoStep(children[1], sportSettings, counter)
+			if err != nil {
+				return nil, err
+			}
+			if !on.free && !off.free && !on.ramp && !off.ramp {
+				elements = append(elements, &zwoIntervals{
+					Repeat:      step.Repetitions,
+					OnDuration:  on.duration,
+					OffDuration: off.duration,
+					OnPower:     on.start,
+					OffPower:    off.start,
+				})
+				continue
+			}
+		}
+
+		for i := 0; i < step.Repetitions; i++ {
+			*counter = start
+			repetition, err := zwoSteps(children, sportSettings, counter)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, repetition...)
+		}
+	}
+	return elements, nil
+}
+
+func newZwoStep(step intervals.WorkoutStep, sportSettings *intervals.AthleteSportSettings, counter *int) (*zwoStep, error) {
+	index := *counter
+	*counter++
+	if step.Duration <= 0 {
+		return nil, stepError(index, step, "ZWO files need a duration")
+	}
+
+	resolved := &zwoStep{duration: int(math.Round(float64(step.Duration))), ramp: step.Ramp}
+	target, ok := step.AbsoluteTarget(sportSettings)
+	if !ok || target.Kind == intervals.TargetHeartRate {
+		resolved.free = true
+		return resolved, nil
+	}
+
+	var threshold float32
+	if target.Kind == intervals.TargetPower {
+		threshold = float32(sportSettings.Ftp)
+	} else {
+		threshold = sportSettings.ThresholdPace
+	}
+	if threshold <= 0 {
+		return nil, stepError(index, step, "threshold is missing from sport settings")
+	}
+
+	resolved.start = roundFraction(target.Start / threshold)
+	resolved.end = roundFraction(target.End / threshold)
+	if !step.Ramp {
+		// ranges are held at their middle
+		resolved.start = roundFraction((target.Start + target.End) / 2 / threshold)
+		resolved.end = resolved.start
+	}
+	return resolved, nil
+}
+
+func (s *zwoStep) element(step intervals.WorkoutStep) any {
+	switch {
+	case s.free:
+		return &zwoFreeRide{Duration: s.duration}
+	case step.Warmup:
+		return &zwoRamp{XMLName: xml.Name{Local: "Warmup"}, Duration: s.duration, PowerLow: s.start, PowerHigh: s.end}
+	case step.Cooldown:
+		return &zwoRamp{XMLName: xml.Name{Local: "Cooldown"}, Duration: s.duration, PowerLow: s.start, PowerHigh: s.end}
+	case s.ramp:
+		return &zwoRamp{XMLName: xml.Name{Local: "Ramp"}, Duration: s.duration, PowerLow: s.start, PowerHigh: s.end}
+	default:
+		return &zwoSteadyState{Duration: s.duration, Power: s.start}
+	}
+}
+
+func roundFraction(value float32) float64 {
+	return math.Round(float64(value)*1000) / 1000
+}